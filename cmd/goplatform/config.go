@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+type profile struct {
+	Uri        string `yaml:"uri"`
+	ApiKey     string `yaml:"apiKey"`
+	Project    string `yaml:"project"`
+	SkipVerify bool   `yaml:"skipVerify"`
+}
+
+type configFile struct {
+	Profiles map[string]profile `yaml:"profiles"`
+}
+
+// loadProfile merges, in increasing order of precedence, the profile file,
+// the GOPLATFORM_* environment variables and the command line flags.
+func loadProfile(path, name string, flags profile) (profile, error) {
+	var res profile
+
+	explicit := name != "" || os.Getenv("GOPLATFORM_PROFILE") != ""
+	if name == "" {
+		name = os.Getenv("GOPLATFORM_PROFILE")
+	}
+	if name == "" {
+		name = "default"
+	}
+
+	if path == "" {
+		path = os.Getenv("GOPLATFORM_CONFIG")
+	}
+	if path == "" {
+		if dir, err := os.UserConfigDir(); err == nil {
+			path = filepath.Join(dir, "goplatform", "config.yaml")
+		}
+	}
+
+	if path != "" {
+		b, err := os.ReadFile(path) //nolint:gosec
+		switch {
+		case err == nil:
+			var file configFile
+			if err := yaml.Unmarshal(b, &file); err != nil {
+				return res, fmt.Errorf("config %s: %w", path, err)
+			}
+			p, ok := file.Profiles[name]
+			if !ok && explicit {
+				return res, fmt.Errorf("config %s: profile %q not found", path, name)
+			}
+			res = p
+		case errors.Is(err, os.ErrNotExist):
+			if explicit {
+				return res, fmt.Errorf("config %s: profile %q not found", path, name)
+			}
+		default:
+			return res, err
+		}
+	}
+
+	if v := os.Getenv("GOPLATFORM_URI"); v != "" {
+		res.Uri = v
+	}
+	if v := os.Getenv("GOPLATFORM_API_KEY"); v != "" {
+		res.ApiKey = v
+	}
+	if v := os.Getenv("GOPLATFORM_PROJECT"); v != "" {
+		res.Project = v
+	}
+	if v := os.Getenv("GOPLATFORM_SKIP_VERIFY"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return res, fmt.Errorf("GOPLATFORM_SKIP_VERIFY: %w", err)
+		}
+		res.SkipVerify = b
+	}
+
+	if flags.Uri != "" {
+		res.Uri = flags.Uri
+	}
+	if flags.ApiKey != "" {
+		res.ApiKey = flags.ApiKey
+	}
+	if flags.Project != "" {
+		res.Project = flags.Project
+	}
+	if flags.SkipVerify {
+		res.SkipVerify = true
+	}
+
+	if res.Uri == "" {
+		return res, errors.New("missing platform uri, set -uri, GOPLATFORM_URI or a profile")
+	}
+
+	return res, nil
+}
@@ -0,0 +1,410 @@
+// Command goplatform is a command-line client for Apio Platform.
+//
+// Usage:
+//
+//	goplatform [flags] <resource> <command> [arguments]
+//
+// The platform uri, api key and project are read from the flags, the
+// GOPLATFORM_URI, GOPLATFORM_API_KEY and GOPLATFORM_PROJECT environment
+// variables or a profile of the config file, in this order.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/ApioIoT/goplatform/v2"
+)
+
+const (
+	exitOK           = 0
+	exitError        = 1
+	exitUsage        = 2
+	exitUnauthorized = 3
+	exitNotFound     = 4
+	exitClientError  = 5
+	exitServerError  = 6
+)
+
+const usage = `Usage: goplatform [flags] <resource> <command> [arguments]
+
+Resources and commands:
+  projects list
+  devices list
+  devices get <uuid>
+  nodes list
+  devicetypes get <uuid>
+  rules list
+  rules export [-format yaml|json] <dir>
+  events create -type <type> -source <source> [-description <text>] [-data <json>]
+  commands send -name <name> [-device <uuid>] [-node <uuid>] [-params <json>]
+
+Flags:
+`
+
+type usageError struct {
+	err error
+}
+
+func (e usageError) Error() string {
+	return e.err.Error()
+}
+
+type app struct {
+	stdout  io.Writer
+	output  string
+	project string
+	client  goplatform.Platform
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	os.Exit(run(ctx, os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("goplatform", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, usage) //nolint:errcheck
+		fs.PrintDefaults()
+	}
+
+	var flags profile
+	var profileName, configPath, output string
+	var timeout time.Duration
+	fs.StringVar(&flags.Uri, "uri", "", "platform uri")
+	fs.StringVar(&flags.ApiKey, "api-key", "", "platform api key")
+	fs.StringVar(&flags.Project, "project", "", "project uuid")
+	fs.BoolVar(&flags.SkipVerify, "insecure", false, "skip TLS certificate verification")
+	fs.StringVar(&profileName, "profile", "", "profile of the config file")
+	fs.StringVar(&configPath, "config", "", "config file")
+	fs.StringVar(&output, "o", outputTable, "output format: table, json or yaml")
+	fs.DurationVar(&timeout, "timeout", 30*time.Second, "request timeout")
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	if fs.NArg() < 2 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	// Checked before dispatching, so that commands are not sent when their
+	// result cannot be rendered
+	switch output {
+	case outputTable, outputJSON, outputYAML:
+	default:
+		fmt.Fprintf(stderr, "goplatform: unknown output format %q\n", output) //nolint:errcheck
+		return exitUsage
+	}
+
+	cfg, err := loadProfile(configPath, profileName, flags)
+	if err != nil {
+		fmt.Fprintf(stderr, "goplatform: %v\n", err) //nolint:errcheck
+		return exitUsage
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	a := app{
+		stdout:  stdout,
+		output:  output,
+		project: cfg.Project,
+		client: goplatform.New(goplatform.Config{
+			Uri:        cfg.Uri,
+			ApiKey:     cfg.ApiKey,
+			SkipVerify: cfg.SkipVerify,
+		}),
+	}
+
+	if err := a.dispatch(ctx, fs.Arg(0), fs.Arg(1), fs.Args()[2:]); err != nil {
+		fmt.Fprintf(stderr, "goplatform: %v\n", err) //nolint:errcheck
+		return exitCode(err)
+	}
+
+	return exitOK
+}
+
+// exitCode maps API errors on distinct exit codes so that scripts can tell
+// authentication, missing resources and server failures apart.
+func exitCode(err error) int {
+	var uerr usageError
+	if errors.As(err, &uerr) {
+		return exitUsage
+	}
+
+	var apiErr *goplatform.APIError
+	if !errors.As(err, &apiErr) {
+		return exitError
+	}
+
+	switch {
+	case apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden:
+		return exitUnauthorized
+	case apiErr.StatusCode == http.StatusNotFound:
+		return exitNotFound
+	case apiErr.StatusCode >= 500:
+		return exitServerError
+	default:
+		return exitClientError
+	}
+}
+
+func (a app) dispatch(ctx context.Context, resource, command string, args []string) error {
+	switch resource + " " + command {
+	case "projects list":
+		return a.projectsList(ctx)
+	case "devices list":
+		return a.devicesList(ctx)
+	case "devices get":
+		return a.devicesGet(ctx, args)
+	case "nodes list":
+		return a.nodesList(ctx)
+	case "devicetypes get":
+		return a.deviceTypesGet(ctx, args)
+	case "rules list":
+		return a.rulesList(ctx)
+	case "rules export":
+		return a.rulesExport(ctx, args)
+	case "events create":
+		return a.eventsCreate(ctx, args)
+	case "commands send":
+		return a.commandsSend(ctx, args)
+	default:
+		return usageError{fmt.Errorf("unknown command %q", strings.TrimSpace(resource+" "+command))}
+	}
+}
+
+func (a app) getProject(ctx context.Context) (goplatform.Project, error) {
+	if a.project == "" {
+		return goplatform.Project{}, usageError{errors.New("missing project, set -project, GOPLATFORM_PROJECT or a profile")}
+	}
+	return a.client.GetProject(ctx, a.project)
+}
+
+func (a app) projectsList(ctx context.Context) error {
+	projects, err := a.client.GetProjects(ctx)
+	if err != nil {
+		return err
+	}
+
+	t := table{header: []string{"UUID", "NAME", "DESCRIPTION"}}
+	for _, p := range projects {
+		t.rows = append(t.rows, []string{p.Uuid, p.Name, p.Description})
+	}
+
+	return render(a.stdout, a.output, projects, t)
+}
+
+func deviceRow(d goplatform.Device) []string {
+	return []string{d.Uuid, d.Name, d.NodeID, d.DeviceTypeID, string(d.ConnectivityStatus)}
+}
+
+var deviceHeader = []string{"UUID", "NAME", "NODE", "DEVICE TYPE", "STATUS"}
+
+func (a app) devicesList(ctx context.Context) error {
+	project, err := a.getProject(ctx)
+	if err != nil {
+		return err
+	}
+
+	devices, err := project.GetDevices(ctx)
+	if err != nil {
+		return err
+	}
+
+	t := table{header: deviceHeader}
+	for _, d := range devices {
+		t.rows = append(t.rows, deviceRow(d))
+	}
+
+	return render(a.stdout, a.output, devices, t)
+}
+
+func (a app) devicesGet(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return usageError{errors.New("usage: devices get <uuid>")}
+	}
+
+	project, err := a.getProject(ctx)
+	if err != nil {
+		return err
+	}
+
+	device, err := project.GetDevice(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	return render(a.stdout, a.output, device, table{header: deviceHeader, rows: [][]string{deviceRow(device)}})
+}
+
+func (a app) nodesList(ctx context.Context) error {
+	project, err := a.getProject(ctx)
+	if err != nil {
+		return err
+	}
+
+	nodes, err := project.GetNodes(ctx)
+	if err != nil {
+		return err
+	}
+
+	t := table{header: []string{"UUID", "NAME", "MODEL", "STATUS"}}
+	for _, n := range nodes {
+		t.rows = append(t.rows, []string{n.Uuid, n.Name, n.Model, string(n.ConnectivityStatus)})
+	}
+
+	return render(a.stdout, a.output, nodes, t)
+}
+
+func (a app) deviceTypesGet(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return usageError{errors.New("usage: devicetypes get <uuid>")}
+	}
+
+	project, err := a.getProject(ctx)
+	if err != nil {
+		return err
+	}
+
+	deviceType, err := project.GetDeviceType(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	t := table{
+		header: []string{"UUID", "NAME", "MANUFACTURER", "MODEL"},
+		rows:   [][]string{{deviceType.Uuid, deviceType.Name, deviceType.Manufacturer, deviceType.Model}},
+	}
+
+	return render(a.stdout, a.output, deviceType, t)
+}
+
+func (a app) rulesList(ctx context.Context) error {
+	project, err := a.getProject(ctx)
+	if err != nil {
+		return err
+	}
+
+	rules, err := project.GetRules(ctx)
+	if err != nil {
+		return err
+	}
+
+	t := table{header: []string{"UUID", "NAME", "STATUS", "MODE"}}
+	for _, r := range rules {
+		t.rows = append(t.rows, []string{r.Uuid, r.Name, r.Status, r.Mode})
+	}
+
+	return render(a.stdout, a.output, rules, t)
+}
+
+func (a app) rulesExport(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("rules export", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	format := fs.String("format", string(goplatform.RULE_FILE_YAML), "file format: yaml or json")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		return usageError{errors.New("usage: rules export [-format yaml|json] <dir>")}
+	}
+
+	project, err := a.getProject(ctx)
+	if err != nil {
+		return err
+	}
+
+	return goplatform.ExportRules(ctx, project, fs.Arg(0), goplatform.RuleFileFormat(*format))
+}
+
+func (a app) eventsCreate(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("events create", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	eventType := fs.String("type", "", "event type")
+	source := fs.String("source", "", "event source")
+	description := fs.String("description", "", "event description")
+	data := fs.String("data", "", "event data as JSON")
+	if err := fs.Parse(args); err != nil || *eventType == "" || *source == "" {
+		return usageError{errors.New("usage: events create -type <type> -source <source> [-description <text>] [-data <json>]")}
+	}
+
+	event := goplatform.Event{
+		Type:        *eventType,
+		Source:      *source,
+		Description: *description,
+		EventTime:   time.Now(),
+	}
+	if *data != "" {
+		if err := json.Unmarshal([]byte(*data), &event.Data); err != nil {
+			return usageError{fmt.Errorf("invalid -data: %w", err)}
+		}
+	}
+
+	project, err := a.getProject(ctx)
+	if err != nil {
+		return err
+	}
+
+	return project.CreateEvent(ctx, event)
+}
+
+func (a app) commandsSend(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("commands send", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	name := fs.String("name", "", "command name")
+	deviceId := fs.String("device", "", "device uuid")
+	nodeId := fs.String("node", "", "node uuid")
+	params := fs.String("params", "", "command parameters as JSON")
+	if err := fs.Parse(args); err != nil || *name == "" {
+		return usageError{errors.New("usage: commands send -name <name> [-device <uuid>] [-node <uuid>] [-params <json>]")}
+	}
+
+	project, err := a.getProject(ctx)
+	if err != nil {
+		return err
+	}
+
+	req := goplatform.CommandRequest{
+		Name:      *name,
+		ProjectId: project.Uuid,
+	}
+	if *deviceId != "" {
+		req.DeviceId = deviceId
+	}
+	if *nodeId != "" {
+		req.NodeId = nodeId
+	}
+	if *params != "" {
+		if err := json.Unmarshal([]byte(*params), &req.Parameters); err != nil {
+			return usageError{fmt.Errorf("invalid -params: %w", err)}
+		}
+	}
+
+	command := req.MakeCommand()
+	if err := project.SendCommand(ctx, command); err != nil {
+		return err
+	}
+
+	t := table{
+		header: []string{"UUID", "NAME", "STATUS"},
+		rows:   [][]string{{command.Uuid, command.Name, string(command.Status)}},
+	}
+
+	return render(a.stdout, a.output, command, t)
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ApioIoT/goplatform/v2"
+	"github.com/h2non/gock"
+)
+
+const (
+	API_URI    = "http://localhost:8080"
+	API_KEY    = "my-api-key"
+	PROJECT_ID = "my-project-id"
+)
+
+func runCommand(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	args = append([]string{"-uri", API_URI, "-api-key", API_KEY, "-project", PROJECT_ID, "-config", os.DevNull}, args...)
+	code := run(context.Background(), args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func mockProject() {
+	gock.New(API_URI).
+		Get("/projects/" + PROJECT_ID).
+		Reply(200).
+		JSON(map[string]any{"status": true, "data": map[string]any{"uuid": PROJECT_ID, "name": "test"}})
+}
+
+func TestRun(t *testing.T) {
+	defer gock.Off()
+
+	t.Run("Devices list", func(t *testing.T) {
+		mockProject()
+		gock.New(API_URI).
+			Get("/projects/" + PROJECT_ID + "/devices").
+			Reply(200).
+			JSON(map[string]any{"status": true, "data": []map[string]any{{"uuid": "my-device-id", "name": "sensor"}}})
+
+		code, stdout, stderr := runCommand("-o", "json", "devices", "list")
+		if code != exitOK {
+			t.Fatalf("expected exit code %d got %d: %s", exitOK, code, stderr)
+		}
+
+		var devices []goplatform.Device
+		if err := json.Unmarshal([]byte(stdout), &devices); err != nil {
+			t.Fatal(err)
+		}
+		if len(devices) != 1 || devices[0].Uuid != "my-device-id" {
+			t.Fatalf("expected device my-device-id got %+v", devices)
+		}
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		mockProject()
+		gock.New(API_URI).
+			Get("/projects/" + PROJECT_ID + "/devices/missing").
+			Reply(404).
+			JSON(map[string]any{"status": false, "error": map[string]any{"name": "NotFound", "message": "device not found"}})
+
+		code, _, stderr := runCommand("devices", "get", "missing")
+		if code != exitNotFound {
+			t.Fatalf("expected exit code %d got %d: %s", exitNotFound, code, stderr)
+		}
+	})
+
+	t.Run("Invalid output", func(t *testing.T) {
+		gock.Off()
+		mockProject()
+		gock.New(API_URI).
+			Post("/projects/" + PROJECT_ID + "/commands").
+			Reply(200)
+
+		code, _, stderr := runCommand("-o", "bogus", "commands", "send", "-name", "reboot", "-device", "my-device-id")
+		if code != exitUsage {
+			t.Fatalf("expected exit code %d got %d", exitUsage, code)
+		}
+		if !strings.Contains(stderr, "unknown output format") {
+			t.Fatalf("expected output format error got %q", stderr)
+		}
+		if len(gock.Pending()) != 2 {
+			t.Fatal("expected no request to be sent")
+		}
+	})
+
+	t.Run("Unknown command", func(t *testing.T) {
+		code, _, _ := runCommand("devices", "delete")
+		if code != exitUsage {
+			t.Fatalf("expected exit code %d got %d", exitUsage, code)
+		}
+	})
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		code int
+	}{
+		{errors.New("boom"), exitError},
+		{usageError{errors.New("bad flag")}, exitUsage},
+		{&goplatform.APIError{StatusCode: 401}, exitUnauthorized},
+		{&goplatform.APIError{StatusCode: 403}, exitUnauthorized},
+		{fmt.Errorf("wrapped: %w", &goplatform.APIError{StatusCode: 404}), exitNotFound},
+		{&goplatform.APIError{StatusCode: 422}, exitClientError},
+		{&goplatform.APIError{StatusCode: 503}, exitServerError},
+	}
+
+	for _, test := range tests {
+		if code := exitCode(test.err); code != test.code {
+			t.Fatalf("expected exit code %d for %v got %d", test.code, test.err, code)
+		}
+	}
+}
+
+func TestLoadProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	config := "profiles:\n  default:\n    uri: http://default\n    apiKey: default-key\n    project: default-project\n  staging:\n    uri: http://staging\n"
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"GOPLATFORM_PROFILE", "GOPLATFORM_URI", "GOPLATFORM_API_KEY", "GOPLATFORM_PROJECT", "GOPLATFORM_SKIP_VERIFY"} {
+		t.Setenv(name, "")
+	}
+
+	t.Run("Default profile", func(t *testing.T) {
+		p, err := loadProfile(path, "", profile{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Uri != "http://default" || p.ApiKey != "default-key" || p.Project != "default-project" {
+			t.Fatalf("expected default profile got %+v", p)
+		}
+	})
+
+	t.Run("Precedence", func(t *testing.T) {
+		t.Setenv("GOPLATFORM_PROFILE", "staging")
+		t.Setenv("GOPLATFORM_API_KEY", "env-key")
+
+		p, err := loadProfile(path, "", profile{Project: "flag-project"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Uri != "http://staging" || p.ApiKey != "env-key" || p.Project != "flag-project" {
+			t.Fatalf("expected staging profile with overrides got %+v", p)
+		}
+	})
+
+	t.Run("Missing profile", func(t *testing.T) {
+		if _, err := loadProfile(path, "production", profile{}); err == nil {
+			t.Fatal("expected error for a missing profile")
+		}
+		if _, err := loadProfile(filepath.Join(t.TempDir(), "missing.yaml"), "", profile{}); err == nil {
+			t.Fatal("expected error for a missing uri")
+		}
+	})
+}
+
+func TestRender(t *testing.T) {
+	value := map[string]any{"uuid": "my-device-id"}
+	tab := table{header: []string{"UUID"}, rows: [][]string{{"my-device-id"}}}
+
+	expected := map[string]string{
+		outputTable: "UUID\nmy-device-id\n",
+		outputJSON:  "{\n  \"uuid\": \"my-device-id\"\n}\n",
+		outputYAML:  "uuid: my-device-id\n",
+	}
+	for format, want := range expected {
+		var b bytes.Buffer
+		if err := render(&b, format, value, tab); err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != want {
+			t.Fatalf("expected %s output %q got %q", format, want, b.String())
+		}
+	}
+
+	var uerr usageError
+	if err := render(&bytes.Buffer{}, "bogus", value, tab); !errors.As(err, &uerr) {
+		t.Fatalf("expected usage error got %v", err)
+	}
+}
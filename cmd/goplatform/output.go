@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// table is the tabular rendering of a value.
+type table struct {
+	header []string
+	rows   [][]string
+}
+
+func render(w io.Writer, format string, value any, t table) error {
+	switch format {
+	case outputTable, "":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(t.header, "\t")) //nolint:errcheck
+		for _, row := range t.rows {
+			fmt.Fprintln(tw, strings.Join(row, "\t")) //nolint:errcheck
+		}
+		return tw.Flush()
+	case outputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(value)
+	case outputYAML:
+		// Round trip through JSON so that the json tags of the SDK apply
+		b, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		var doc any
+		if err := json.Unmarshal(b, &doc); err != nil {
+			return err
+		}
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(doc); err != nil {
+			return err
+		}
+		return enc.Close()
+	default:
+		return usageError{fmt.Errorf("unknown output format %q", format)}
+	}
+}
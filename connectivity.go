@@ -0,0 +1,99 @@
+package goplatform
+
+import "time"
+
+type ConnectivityStatus string
+
+const (
+	CONNECTIVITY_STATUS_CONNECTED    ConnectivityStatus = "connected"
+	CONNECTIVITY_STATUS_DISCONNECTED ConnectivityStatus = "disconnected"
+)
+
+// Connected reports whether the node is connected to the platform.
+func (n Node) Connected() bool {
+	return n.ConnectivityStatus == CONNECTIVITY_STATUS_CONNECTED
+}
+
+// LastConnection returns when the node last connected. It reports false when
+// the node never connected.
+func (n Node) LastConnection() (time.Time, bool) {
+	return parseConnectivityTime(n.LastConnectionAt)
+}
+
+// LastDisconnection returns when the node last disconnected.
+func (n Node) LastDisconnection() (time.Time, bool) {
+	return parseConnectivityTime(n.LastDisconnectionAt)
+}
+
+// LastCommunication returns when the node last sent data.
+func (n Node) LastCommunication() (time.Time, bool) {
+	return parseConnectivityTime(n.LastCommunicationAt)
+}
+
+// OfflineFor returns how long the node has been disconnected at now. It
+// reports false when the node is connected or the disconnection time is not
+// known.
+func (n Node) OfflineFor(now time.Time) (time.Duration, bool) {
+	if n.Connected() {
+		return 0, false
+	}
+	return offlineFor(now, n.LastDisconnectionAt, n.LastCommunicationAt)
+}
+
+// Connected reports whether the device is connected to the platform.
+func (d Device) Connected() bool {
+	return d.ConnectivityStatus == CONNECTIVITY_STATUS_CONNECTED
+}
+
+// LastConnection returns when the device last connected. It reports false
+// when the device never connected.
+func (d Device) LastConnection() (time.Time, bool) {
+	return parseConnectivityTime(d.LastConnectionAt)
+}
+
+// LastDisconnection returns when the device last disconnected.
+func (d Device) LastDisconnection() (time.Time, bool) {
+	return parseConnectivityTime(d.LastDisconnectionAt)
+}
+
+// LastCommunication returns when the device last sent data.
+func (d Device) LastCommunication() (time.Time, bool) {
+	return parseConnectivityTime(d.LastCommunicationAt)
+}
+
+// LastActivity returns when the device was last active.
+func (d Device) LastActivity() (time.Time, bool) {
+	return parseConnectivityTime(d.LastActivityAt)
+}
+
+// OfflineFor returns how long the device has been disconnected at now. It
+// reports false when the device is connected or the disconnection time is
+// not known.
+func (d Device) OfflineFor(now time.Time) (time.Duration, bool) {
+	if d.Connected() {
+		return 0, false
+	}
+	return offlineFor(now, d.LastDisconnectionAt, d.LastCommunicationAt, d.LastActivityAt)
+}
+
+// offlineFor measures the time elapsed since the first known timestamp.
+func offlineFor(now time.Time, since ...string) (time.Duration, bool) {
+	for _, s := range since {
+		if t, ok := parseConnectivityTime(s); ok {
+			if now.Before(t) {
+				return 0, true
+			}
+			return now.Sub(t), true
+		}
+	}
+	return 0, false
+}
+
+// parseConnectivityTime parses a connectivity timestamp, which the platform
+// sets to "never", empty or null until the first event.
+func parseConnectivityTime(s string) (time.Time, bool) {
+	if s == "" || s == "never" || s == "null" {
+		return time.Time{}, false
+	}
+	return parseTimestamp(s)
+}
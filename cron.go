@@ -0,0 +1,270 @@
+package goplatform
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Crontab is a parsed cron expression.
+type Crontab struct {
+	expr    string
+	second  uint64
+	minute  uint64
+	hour    uint64
+	dom     uint64
+	month   uint64
+	dow     uint64
+	anyDom  bool
+	anyDow  bool
+	seconds bool
+}
+
+type cronField struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	cronSecond = cronField{name: "second", min: 0, max: 59}
+	cronMinute = cronField{name: "minute", min: 0, max: 59}
+	cronHour   = cronField{name: "hour", min: 0, max: 23}
+	cronDom    = cronField{name: "day of month", min: 1, max: 31}
+	cronMonth  = cronField{name: "month", min: 1, max: 12, names: map[string]int{
+		"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+		"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+	}}
+	cronDow = cronField{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6,
+	}}
+)
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseCrontab parses a cron expression made of five fields (minute, hour,
+// day of month, month, day of week) or six fields with a leading second.
+// Fields accept *, ?, lists, ranges, steps and, for months and days of week,
+// three letter names. The @yearly, @monthly, @weekly, @daily and @hourly
+// macros are supported too.
+func ParseCrontab(expr string) (Crontab, error) {
+	var zero Crontab
+
+	source := strings.TrimSpace(expr)
+	if macro, ok := cronMacros[strings.ToLower(source)]; ok {
+		source = macro
+	}
+
+	fields := strings.Fields(source)
+	if len(fields) != 5 && len(fields) != 6 {
+		return zero, fmt.Errorf("goplatform: crontab %q: expected 5 or 6 fields, got %d", expr, len(fields))
+	}
+
+	c := Crontab{expr: expr, second: 1}
+	if len(fields) == 6 {
+		c.seconds = true
+		second, err := parseCronField(fields[0], cronSecond)
+		if err != nil {
+			return zero, fmt.Errorf("goplatform: crontab %q: %w", expr, err)
+		}
+		c.second = second
+		fields = fields[1:]
+	}
+
+	var err error
+	if c.minute, err = parseCronField(fields[0], cronMinute); err != nil {
+		return zero, fmt.Errorf("goplatform: crontab %q: %w", expr, err)
+	}
+	if c.hour, err = parseCronField(fields[1], cronHour); err != nil {
+		return zero, fmt.Errorf("goplatform: crontab %q: %w", expr, err)
+	}
+	if c.dom, err = parseCronField(fields[2], cronDom); err != nil {
+		return zero, fmt.Errorf("goplatform: crontab %q: %w", expr, err)
+	}
+	if c.month, err = parseCronField(fields[3], cronMonth); err != nil {
+		return zero, fmt.Errorf("goplatform: crontab %q: %w", expr, err)
+	}
+	if c.dow, err = parseCronField(fields[4], cronDow); err != nil {
+		return zero, fmt.Errorf("goplatform: crontab %q: %w", expr, err)
+	}
+
+	// Sunday can be written as both 0 and 7
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+
+	c.anyDom = fields[2] == "*" || fields[2] == "?"
+	c.anyDow = fields[4] == "*" || fields[4] == "?"
+
+	return c, nil
+}
+
+func parseCronField(field string, spec cronField) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepStr)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step %q in %s", stepStr, spec.name)
+			}
+			step = n
+		}
+
+		var from, to int
+		switch {
+		case rng == "*" || rng == "?":
+			from, to = spec.min, spec.max
+		case strings.Contains(rng, "-"):
+			lo, hi, _ := strings.Cut(rng, "-")
+			var err error
+			if from, err = parseCronValue(lo, spec); err != nil {
+				return 0, err
+			}
+			if to, err = parseCronValue(hi, spec); err != nil {
+				return 0, err
+			}
+			if from > to {
+				return 0, fmt.Errorf("invalid range %q in %s", rng, spec.name)
+			}
+		default:
+			n, err := parseCronValue(rng, spec)
+			if err != nil {
+				return 0, err
+			}
+			from, to = n, n
+			if hasStep {
+				to = spec.max
+			}
+		}
+
+		for i := from; i <= to; i += step {
+			bits |= 1 << i
+		}
+	}
+
+	return bits, nil
+}
+
+func parseCronValue(s string, spec cronField) (int, error) {
+	if n, ok := spec.names[strings.ToUpper(s)]; ok {
+		return n, nil
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q in %s", s, spec.name)
+	}
+	if n < spec.min || n > spec.max {
+		return 0, fmt.Errorf("value %d out of range [%d-%d] in %s", n, spec.min, spec.max, spec.name)
+	}
+
+	return n, nil
+}
+
+// String returns the source expression.
+func (c Crontab) String() string {
+	return c.expr
+}
+
+// Next returns the first fire time strictly after from, in the location of
+// from. It returns the zero time when the expression never fires, for
+// example on February 30th.
+func (c Crontab) Next(from time.Time) time.Time {
+	t := from.Truncate(time.Second).Add(time.Second)
+	if !c.seconds {
+		t = from.Truncate(time.Minute).Add(time.Minute)
+	}
+
+	limit := t.AddDate(5, 0, 0)
+	loc := t.Location()
+
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !c.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Truncate(time.Minute).Add(time.Minute)
+			continue
+		}
+		if c.second&(1<<uint(t.Second())) == 0 {
+			t = t.Add(time.Second)
+			continue
+		}
+		return t
+	}
+
+	return time.Time{}
+}
+
+// NextFireTimes returns the next n fire times strictly after from, computed
+// in location. A nil location means the location of from.
+func (c Crontab) NextFireTimes(n int, from time.Time, location *time.Location) []time.Time {
+	if location != nil {
+		from = from.In(location)
+	}
+
+	res := make([]time.Time, 0, n)
+	for len(res) < n {
+		next := c.Next(from)
+		if next.IsZero() {
+			break
+		}
+		res = append(res, next)
+		from = next
+	}
+
+	return res
+}
+
+func (c Crontab) matchDay(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+
+	switch {
+	case c.anyDom && c.anyDow:
+		return true
+	case c.anyDom:
+		return dow
+	case c.anyDow:
+		return dom
+	default:
+		return dom || dow
+	}
+}
+
+// NextFireTimes returns the next n fire times of a cron trigger strictly
+// after from, computed in location.
+func (t RuleTrigger) NextFireTimes(n int, from time.Time, location *time.Location) ([]time.Time, error) {
+	if t.Type != RULE_TRIGGER_CRON {
+		return nil, fmt.Errorf("goplatform: rule trigger %s: not a cron trigger", t.Type)
+	}
+
+	c, err := ParseCrontab(deref(t.Crontab))
+	if err != nil {
+		return nil, err
+	}
+
+	return c.NextFireTimes(n, from, location), nil
+}
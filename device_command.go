@@ -0,0 +1,139 @@
+package goplatform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+type SendCommandOptions struct {
+	Metadata       map[string]any
+	DownlinkRetry  *CommandRequestRetryOption
+	ExecutionRetry *CommandRequestRetryOption
+	// Validate checks the command against the command catalog of the device
+	// type before sending it. Nodes have no catalog and ignore it.
+	Validate bool
+}
+
+// SendCommand sends the command name with params to the device and returns
+// the created command.
+func (d Device) SendCommand(ctx context.Context, name string, params CommandParameter, options ...SendCommandOptions) (Command, error) {
+	project, err := projectOf(d.platformRef, "device", d.Uuid, d.ProjectID)
+	if err != nil {
+		return Command{}, err
+	}
+
+	_options := commandOptions(options)
+	if _options.Validate {
+		deviceType, err := d.Type(ctx)
+		if err != nil {
+			return Command{}, err
+		}
+		if err := deviceType.ValidateCommand(name, params); err != nil {
+			return Command{}, err
+		}
+	}
+
+	return sendCommandRequest(ctx, project, d.commandRequest(project.Uuid, name, params, _options))
+}
+
+// commandRequest returns the request of a command addressed to the device
+// through its node.
+func (d Device) commandRequest(projectId, name string, params CommandParameter, options SendCommandOptions) CommandRequest {
+	deviceId := d.Uuid
+	req := makeCommandRequest(projectId, name, params, options)
+	req.DeviceId = &deviceId
+	if d.NodeID != "" {
+		nodeId := d.NodeID
+		req.NodeId = &nodeId
+	}
+	return req
+}
+
+// SendCommand sends the command name with params to the node and returns the
+// created command.
+func (n Node) SendCommand(ctx context.Context, name string, params CommandParameter, options ...SendCommandOptions) (Command, error) {
+	project, err := projectOf(n.platformRef, "node", n.Uuid, n.ProjectID)
+	if err != nil {
+		return Command{}, err
+	}
+
+	nodeId := n.Uuid
+	req := makeCommandRequest(project.Uuid, name, params, commandOptions(options))
+	req.NodeId = &nodeId
+
+	return sendCommandRequest(ctx, project, req)
+}
+
+// ValidateCommand checks that name is in the command catalog of the device
+// type and that params match its parameters. Device types without a catalog
+// accept any command.
+func (dt DeviceType) ValidateCommand(name string, params CommandParameter) error {
+	if name == "" {
+		return errors.New("goplatform: command: missing name")
+	}
+	if len(dt.Commands) == 0 {
+		return nil
+	}
+
+	command, ok := dt.Commands[name]
+	if !ok {
+		return fmt.Errorf("goplatform: command %s: not supported by device type %s", name, dt.Name)
+	}
+
+	var errs []error
+	for _, param := range slices.Sorted(maps.Keys(command.Parameters)) {
+		value, ok := params[param]
+		if !ok || value == nil {
+			if command.Parameters[param].Required {
+				errs = append(errs, fmt.Errorf("missing parameter %s", param))
+			}
+			continue
+		}
+		if _, err := coerceValue(command.Parameters[param].Type, value); err != nil {
+			errs = append(errs, fmt.Errorf("parameter %s: %w", param, err))
+		}
+	}
+	for _, param := range slices.Sorted(maps.Keys(params)) {
+		if _, ok := command.Parameters[param]; !ok {
+			errs = append(errs, fmt.Errorf("unknown parameter %s", param))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("goplatform: command %s: %w", name, errors.Join(errs...))
+	}
+
+	return nil
+}
+
+func commandOptions(options []SendCommandOptions) SendCommandOptions {
+	if len(options) > 0 {
+		return options[0]
+	}
+	return SendCommandOptions{}
+}
+
+func makeCommandRequest(projectId, name string, params CommandParameter, options SendCommandOptions) CommandRequest {
+	req := CommandRequest{
+		Name:           name,
+		ProjectId:      projectId,
+		Metadata:       options.Metadata,
+		DownlinkRetry:  options.DownlinkRetry,
+		ExecutionRetry: options.ExecutionRetry,
+	}
+	if params != nil {
+		req.Parameters = CommandParameters{params}
+	}
+	return req
+}
+
+func sendCommandRequest(ctx context.Context, project Project, req CommandRequest) (Command, error) {
+	command := req.MakeCommand()
+	if err := project.SendCommand(ctx, command); err != nil {
+		return Command{}, err
+	}
+	return command, nil
+}
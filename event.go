@@ -0,0 +1,123 @@
+package goplatform
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// EventQuery filters the events of a project. Zero fields are ignored.
+type EventQuery struct {
+	Type   string
+	Source string
+	// From and To bound EventTime, From inclusive and To exclusive.
+	From time.Time
+	To   time.Time
+	// Metadata matches events whose metadata has all the given values.
+	Metadata map[string]string
+	// Limit is the page size, 100 by default.
+	Limit  int
+	Offset int
+}
+
+// EventPage is a page of events matching a query.
+type EventPage struct {
+	Events []Event
+	// Total is the number of events matching the query, -1 when unknown.
+	Total int
+	query EventQuery
+}
+
+type eventsResponse struct {
+	Status bool    `json:"status,omitempty"`
+	Data   []Event `json:"data,omitempty"`
+	Meta   *struct {
+		Total int `json:"total"`
+	} `json:"meta,omitempty"`
+}
+
+func (q EventQuery) values() url.Values {
+	query := url.Values{}
+	if q.Type != "" {
+		query.Set("type", q.Type)
+	}
+	if q.Source != "" {
+		query.Set("source", q.Source)
+	}
+	if !q.From.IsZero() {
+		query.Set("from", q.From.UTC().Format(time.RFC3339Nano))
+	}
+	if !q.To.IsZero() {
+		query.Set("to", q.To.UTC().Format(time.RFC3339Nano))
+	}
+	for _, key := range slices.Sorted(maps.Keys(q.Metadata)) {
+		query.Set("metadata."+key, q.Metadata[key])
+	}
+	query.Set("limit", strconv.Itoa(q.Limit))
+	query.Set("offset", strconv.Itoa(q.Offset))
+	return query
+}
+
+// GetEvents returns a page of the project events matching query, sorted by
+// EventTime. Use Next to walk the following pages.
+func (p Project) GetEvents(ctx context.Context, query EventQuery) (EventPage, error) {
+	if query.Limit <= 0 {
+		query.Limit = 100
+	}
+	if query.Offset < 0 {
+		query.Offset = 0
+	}
+
+	page := EventPage{Total: -1, query: query}
+
+	b, err := p.platformRef.fetchQuery(ctx, httpGet, nil, query.values(), "projects", p.Uuid, "events")
+	if err != nil {
+		return page, err
+	}
+
+	var events eventsResponse
+	if err := json.Unmarshal(b, &events); err != nil {
+		return page, err
+	}
+
+	page.Events = events.Data
+	if events.Meta != nil {
+		page.Total = events.Meta.Total
+	}
+
+	return page, nil
+}
+
+// Next returns the query of the following page, reporting false on the last
+// page. An empty page is always the last one, even before reaching Total.
+func (p EventPage) Next() (EventQuery, bool) {
+	next := p.query
+	next.Offset += len(p.Events)
+
+	if len(p.Events) == 0 {
+		return next, false
+	}
+	if p.Total >= 0 {
+		return next, next.Offset < p.Total
+	}
+	return next, len(p.Events) >= p.query.Limit
+}
+
+func (p Project) GetEvent(ctx context.Context, uuid string) (Event, error) {
+	b, err := p.platformRef.fetch(ctx, httpGet, nil, "projects", p.Uuid, "events", uuid)
+	if err != nil {
+		var zero Event
+		return zero, err
+	}
+
+	var event response[Event]
+	if err := json.Unmarshal(b, &event); err != nil {
+		return event.Data, err
+	}
+
+	return event.Data, nil
+}
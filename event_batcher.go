@@ -0,0 +1,269 @@
+package goplatform
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type EventBatcherOptions struct {
+	// MaxBatchSize flushes the buffer as soon as it holds this many events,
+	// 100 by default.
+	MaxBatchSize int
+	// FlushInterval flushes the buffer periodically, 1s by default.
+	FlushInterval time.Duration
+	// Concurrency is the number of parallel requests used when the bulk
+	// endpoint is not available, 8 by default.
+	Concurrency int
+	// OnFailure is called with the events that could not be sent.
+	OnFailure func([]EventFailure)
+}
+
+// EventFailure is an event the batcher failed to send.
+type EventFailure struct {
+	Event Event
+	Err   error
+}
+
+const (
+	bulkUnknown int32 = iota
+	bulkSupported
+	bulkUnsupported
+)
+
+// EventBatcher buffers events and sends them to the project in batches. It
+// is safe for concurrent use.
+type EventBatcher struct {
+	project Project
+	options EventBatcherOptions
+
+	mu      sync.Mutex
+	pending []Event
+	closed  bool
+	// errs are the failures of background flushes, returned by the next
+	// Flush or Close.
+	errs []error
+
+	send   sync.Mutex
+	bulk   atomic.Int32
+	flush  chan struct{}
+	stop   chan struct{}
+	done   chan struct{}
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewEventBatcher starts a batcher sending events to project. Close must be
+// called to send the buffered events and release its resources.
+func NewEventBatcher(project Project, options ...EventBatcherOptions) *EventBatcher {
+	var _options EventBatcherOptions
+	if len(options) > 0 {
+		_options = options[0]
+	}
+
+	if _options.MaxBatchSize <= 0 {
+		_options.MaxBatchSize = 100
+	}
+	if _options.FlushInterval <= 0 {
+		_options.FlushInterval = time.Second
+	}
+	if _options.Concurrency <= 0 {
+		_options.Concurrency = 8
+	}
+
+	b := &EventBatcher{
+		project: project,
+		options: _options,
+		flush:   make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	b.ctx, b.cancel = context.WithCancel(context.Background())
+
+	go b.run()
+
+	return b
+}
+
+// Add buffers event, filling in its project. It fails once the batcher is
+// closed.
+func (b *EventBatcher) Add(event Event) error {
+	event.ProjectID = b.project.Uuid
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return fmt.Errorf("goplatform: event batcher closed")
+	}
+
+	b.pending = append(b.pending, event)
+	if len(b.pending) >= b.options.MaxBatchSize {
+		select {
+		case b.flush <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// Flush sends the buffered events now. It returns the failures of the
+// flushed events and of the background flushes since the previous call,
+// which are also reported to OnFailure.
+func (b *EventBatcher) Flush(ctx context.Context) error {
+	err := b.flushPending(ctx)
+
+	b.mu.Lock()
+	errs := append(b.errs, err)
+	b.errs = nil
+	b.mu.Unlock()
+
+	return errors.Join(errs...)
+}
+
+func (b *EventBatcher) flushPending(ctx context.Context) error {
+	var errs []error
+	for {
+		b.mu.Lock()
+		n := min(len(b.pending), b.options.MaxBatchSize)
+		batch := b.pending[:n:n]
+		b.pending = b.pending[n:]
+		b.mu.Unlock()
+
+		if len(batch) == 0 {
+			break
+		}
+
+		for _, failure := range b.sendBatch(ctx, batch) {
+			errs = append(errs, fmt.Errorf("goplatform: event %s: %w", failure.Event.Type, failure.Err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Close stops accepting events and sends the buffered ones. When ctx is done
+// the background flush in progress is canceled and the events still buffered
+// are reported as failed.
+func (b *EventBatcher) Close(ctx context.Context) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	b.mu.Unlock()
+
+	close(b.stop)
+	select {
+	case <-b.done:
+	case <-ctx.Done():
+		b.cancel()
+		<-b.done
+	}
+	b.cancel()
+
+	return b.Flush(ctx)
+}
+
+func (b *EventBatcher) run() {
+	defer close(b.done)
+
+	ticker := time.NewTicker(b.options.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.stop:
+			return
+		case <-ticker.C:
+		case <-b.flush:
+		}
+		if err := b.flushPending(b.ctx); err != nil {
+			b.mu.Lock()
+			b.errs = append(b.errs, err)
+			b.mu.Unlock()
+		}
+	}
+}
+
+func (b *EventBatcher) sendBatch(ctx context.Context, batch []Event) []EventFailure {
+	b.send.Lock()
+	defer b.send.Unlock()
+
+	var failures []EventFailure
+	if err := ctx.Err(); err != nil {
+		failures = make([]EventFailure, len(batch))
+		for i, event := range batch {
+			failures[i] = EventFailure{Event: event, Err: err}
+		}
+	} else if b.bulk.Load() != bulkUnsupported {
+		failures = b.sendBulk(ctx, batch)
+	} else {
+		failures = b.sendSingle(ctx, batch)
+	}
+
+	if len(failures) > 0 && b.options.OnFailure != nil {
+		b.options.OnFailure(failures)
+	}
+
+	return failures
+}
+
+// sendBulk posts the batch to the bulk endpoint, falling back to single
+// requests when the platform does not provide it.
+func (b *EventBatcher) sendBulk(ctx context.Context, batch []Event) []EventFailure {
+	body, err := json.Marshal(batch)
+	if err == nil {
+		_, err = b.project.platformRef.fetch(ctx, httpPost, bytes.NewReader(body), "projects", b.project.Uuid, "events", "bulk")
+	}
+
+	var apiErr *APIError
+	switch {
+	case err == nil:
+		b.bulk.Store(bulkSupported)
+		return nil
+	case b.bulk.Load() == bulkUnknown && errors.As(err, &apiErr) &&
+		(apiErr.StatusCode == http.StatusNotFound || apiErr.StatusCode == http.StatusMethodNotAllowed):
+		b.bulk.Store(bulkUnsupported)
+		return b.sendSingle(ctx, batch)
+	}
+
+	failures := make([]EventFailure, len(batch))
+	for i, event := range batch {
+		failures[i] = EventFailure{Event: event, Err: err}
+	}
+	return failures
+}
+
+func (b *EventBatcher) sendSingle(ctx context.Context, batch []Event) []EventFailure {
+	errs := make([]error, len(batch))
+	sem := make(chan struct{}, b.options.Concurrency)
+
+	var wg sync.WaitGroup
+	for i, event := range batch {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			errs[i] = b.project.CreateEvent(ctx, event)
+		}()
+	}
+	wg.Wait()
+
+	var failures []EventFailure
+	for i, err := range errs {
+		if err != nil {
+			failures = append(failures, EventFailure{Event: batch[i], Err: err})
+		}
+	}
+	return failures
+}
@@ -0,0 +1,119 @@
+package goplatform
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"slices"
+	"time"
+)
+
+const FIRMWARE_UPDATE_COMMAND = "firmwareUpdate"
+
+type Firmware struct {
+	Uuid         string    `json:"uuid,omitempty"`
+	ProjectID    string    `json:"projectId"`
+	DeviceTypeID string    `json:"deviceTypeId,omitempty"`
+	Name         string    `json:"name,omitempty"`
+	Version      string    `json:"version"`
+	Description  string    `json:"description,omitempty"`
+	Checksum     string    `json:"checksum,omitempty"`
+	Size         int64     `json:"size,omitempty"`
+	CreatedAt    time.Time `json:"createdAt,omitempty"`
+	UpdatedAt    time.Time `json:"updatedAt,omitempty"`
+}
+
+func (p Project) GetFirmwares(ctx context.Context) ([]Firmware, error) {
+	b, err := p.platformRef.fetch(ctx, httpGet, nil, "projects", p.Uuid, "firmwares")
+	if err != nil {
+		return nil, err
+	}
+
+	var firmwares response[[]Firmware]
+	if err := json.Unmarshal(b, &firmwares); err != nil {
+		return nil, err
+	}
+
+	return firmwares.Data, nil
+}
+
+// UploadFirmware uploads the firmware image read from r. Size and the
+// SHA-256 Checksum of the image are filled in.
+func (p Project) UploadFirmware(ctx context.Context, firmware Firmware, r io.Reader) (Firmware, error) {
+	firmware.ProjectID = p.Uuid
+
+	image, err := io.ReadAll(r)
+	if err != nil {
+		return firmware, err
+	}
+	sum := sha256.Sum256(image)
+	firmware.Checksum = hex.EncodeToString(sum[:])
+	firmware.Size = int64(len(image))
+
+	metadata, err := json.Marshal(firmware)
+	if err != nil {
+		return firmware, err
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	if err := form.WriteField("metadata", string(metadata)); err != nil {
+		return firmware, err
+	}
+	file, err := form.CreateFormFile("file", fmt.Sprintf("%s-%s.bin", firmware.Name, firmware.Version))
+	if err != nil {
+		return firmware, err
+	}
+	if _, err := file.Write(image); err != nil {
+		return firmware, err
+	}
+	if err := form.Close(); err != nil {
+		return firmware, err
+	}
+
+	req, err := p.platformRef.newRequest(ctx, httpPost, &body, "projects", p.Uuid, "firmwares")
+	if err != nil {
+		return firmware, err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	b, err := p.platformRef.do(req)
+	if err != nil {
+		return firmware, err
+	}
+
+	var res response[Firmware]
+	if err := json.Unmarshal(b, &res); err != nil {
+		return firmware, err
+	}
+
+	return res.Data, nil
+}
+
+// UpdateFirmware asks the device to install the firmware version. When the
+// device type lists its firmware versions, version must be one of them.
+func (d Device) UpdateFirmware(ctx context.Context, version string) (Command, error) {
+	if version == "" {
+		return Command{}, fmt.Errorf("goplatform: device %s: missing firmware version", d.Uuid)
+	}
+
+	deviceType, err := d.Type(ctx)
+	if err != nil {
+		return Command{}, err
+	}
+	if len(deviceType.FirmwareVersions) > 0 && !slices.Contains(deviceType.FirmwareVersions, version) {
+		return Command{}, fmt.Errorf("goplatform: device %s: firmware version %s not available for device type %s", d.Uuid, version, deviceType.Name)
+	}
+
+	params := CommandParameter{"version": version}
+	if deviceType.FirmwareID != "" {
+		params["firmwareId"] = deviceType.FirmwareID
+	}
+
+	return d.SendCommand(ctx, FIRMWARE_UPDATE_COMMAND, params)
+}
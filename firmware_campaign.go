@@ -0,0 +1,416 @@
+package goplatform
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+)
+
+type CampaignStatus string
+
+const (
+	CAMPAIGN_RUNNING   CampaignStatus = "running"
+	CAMPAIGN_HALTED    CampaignStatus = "halted"
+	CAMPAIGN_COMPLETED CampaignStatus = "completed"
+)
+
+type CampaignDeviceStatus string
+
+const (
+	CAMPAIGN_DEVICE_PENDING   CampaignDeviceStatus = "pending"
+	CAMPAIGN_DEVICE_UPDATING  CampaignDeviceStatus = "updating"
+	CAMPAIGN_DEVICE_SUCCEEDED CampaignDeviceStatus = "succeeded"
+	CAMPAIGN_DEVICE_FAILED    CampaignDeviceStatus = "failed"
+)
+
+// ErrCampaignHalted is returned by FirmwareCampaign.Run when failures exceed
+// the campaign threshold.
+var ErrCampaignHalted = errors.New("goplatform: firmware campaign halted")
+
+// FirmwareCampaignSpec describes a staged firmware rollout.
+type FirmwareCampaignSpec struct {
+	Version   string   `json:"version"`
+	DeviceIds []string `json:"deviceIds"`
+	// CanaryPercent is the share of devices, 0 to 100, upgraded in the first
+	// wave. At least one device is upgraded.
+	CanaryPercent float64 `json:"canaryPercent"`
+	// WaveSize is the number of devices of the following waves, all the
+	// remaining devices when 0.
+	WaveSize int `json:"waveSize"`
+	// MaxConcurrent bounds the devices upgrading at once, 1 by default.
+	MaxConcurrent int `json:"maxConcurrent"`
+	// FailureThreshold is the share of failed devices among the upgraded
+	// ones, 0 to 1, above which the rollout halts.
+	FailureThreshold float64 `json:"failureThreshold"`
+	// Timeout is how long a device may take to report the new version, 30
+	// minutes by default.
+	Timeout time.Duration `json:"timeout"`
+	// PollInterval is how often upgrading devices are checked, 10s by
+	// default.
+	PollInterval time.Duration `json:"pollInterval"`
+}
+
+// CampaignDevice is the progress of a device in a campaign.
+type CampaignDevice struct {
+	DeviceId  string               `json:"deviceId"`
+	Wave      int                  `json:"wave"`
+	Status    CampaignDeviceStatus `json:"status"`
+	CommandId string               `json:"commandId,omitempty"`
+	StartedAt *time.Time           `json:"startedAt,omitempty"`
+	Error     string               `json:"error,omitempty"`
+}
+
+type campaignState struct {
+	Spec    FirmwareCampaignSpec `json:"spec"`
+	Status  CampaignStatus       `json:"status"`
+	Devices []CampaignDevice     `json:"devices"`
+}
+
+// FirmwareCampaign upgrades devices in waves, persisting its progress to a
+// file so that an interrupted rollout resumes where it stopped.
+type FirmwareCampaign struct {
+	project Project
+	path    string
+
+	mu     sync.Mutex
+	saveMu sync.Mutex
+	state  campaignState
+	// saveErr is the first failure to persist the state, returned by Run.
+	saveErr error
+}
+
+// NewFirmwareCampaign resumes the campaign persisted at path or, when the
+// file does not exist, starts spec. A persisted campaign is only resumed
+// with its own spec, or with the zero spec.
+func NewFirmwareCampaign(project Project, path string, spec FirmwareCampaignSpec) (*FirmwareCampaign, error) {
+	c := &FirmwareCampaign{project: project, path: path}
+
+	resume := !reflect.DeepEqual(spec, FirmwareCampaignSpec{})
+	spec = spec.withDefaults()
+
+	b, err := os.ReadFile(path) //nolint:gosec
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(b, &c.state); err != nil {
+			return nil, fmt.Errorf("goplatform: firmware campaign %s: %w", path, err)
+		}
+		if resume && !reflect.DeepEqual(spec, c.state.Spec) {
+			return nil, fmt.Errorf("goplatform: firmware campaign %s: spec differs from the persisted campaign", path)
+		}
+		return c, nil
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, err
+	}
+
+	if spec.Version == "" {
+		return nil, errors.New("goplatform: firmware campaign: missing version")
+	}
+	if len(spec.DeviceIds) == 0 {
+		return nil, errors.New("goplatform: firmware campaign: missing devices")
+	}
+
+	c.state = campaignState{Spec: spec, Status: CAMPAIGN_RUNNING}
+
+	canary := max(1, int(math.Ceil(float64(len(spec.DeviceIds))*spec.CanaryPercent/100)))
+	for i, id := range spec.DeviceIds {
+		wave := 0
+		if i >= canary {
+			wave = 1
+			if spec.WaveSize > 0 {
+				wave += (i - canary) / spec.WaveSize
+			}
+		}
+		c.state.Devices = append(c.state.Devices, CampaignDevice{DeviceId: id, Wave: wave, Status: CAMPAIGN_DEVICE_PENDING})
+	}
+
+	if err := c.save(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (s FirmwareCampaignSpec) withDefaults() FirmwareCampaignSpec {
+	if s.MaxConcurrent <= 0 {
+		s.MaxConcurrent = 1
+	}
+	if s.Timeout <= 0 {
+		s.Timeout = 30 * time.Minute
+	}
+	if s.PollInterval <= 0 {
+		s.PollInterval = 10 * time.Second
+	}
+	return s
+}
+
+// Status returns the campaign status and a copy of the device progress.
+func (c *FirmwareCampaign) Status() (CampaignStatus, []CampaignDevice) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.state.Status, append([]CampaignDevice{}, c.state.Devices...)
+}
+
+// Run upgrades the devices wave after wave until the campaign completes, it
+// halts or ctx is canceled. It returns ErrCampaignHalted when the failures
+// exceed the threshold; already halted campaigns are not resumed. Devices
+// the platform could not be reached for are retried until Timeout, then left
+// to the next Run, which also stops at the first wave that cannot complete
+// or when the state cannot be persisted.
+func (c *FirmwareCampaign) Run(ctx context.Context) error {
+	c.mu.Lock()
+	status := c.state.Status
+	waves := 0
+	for _, d := range c.state.Devices {
+		waves = max(waves, d.Wave+1)
+	}
+	c.mu.Unlock()
+
+	switch status {
+	case CAMPAIGN_HALTED:
+		return ErrCampaignHalted
+	case CAMPAIGN_COMPLETED:
+		return nil
+	}
+
+	for wave := 0; wave < waves; wave++ {
+		if err := c.runWave(ctx, wave); err != nil {
+			return err
+		}
+	}
+
+	c.mu.Lock()
+	c.state.Status = CAMPAIGN_COMPLETED
+	c.mu.Unlock()
+
+	return c.save()
+}
+
+func (c *FirmwareCampaign) runWave(ctx context.Context, wave int) error {
+	c.mu.Lock()
+	sem := make(chan struct{}, c.state.Spec.MaxConcurrent)
+	devices := append([]CampaignDevice{}, c.state.Devices...)
+	c.mu.Unlock()
+
+	var wg sync.WaitGroup
+	var errsMu sync.Mutex
+	var errs []error
+
+	for i, device := range devices {
+		if device.Wave != wave || device.Status == CAMPAIGN_DEVICE_SUCCEEDED || device.Status == CAMPAIGN_DEVICE_FAILED {
+			continue
+		}
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil || c.exceeded() {
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := c.upgrade(ctx, i); err != nil {
+				errsMu.Lock()
+				errs = append(errs, err)
+				errsMu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if c.exceeded() {
+		c.mu.Lock()
+		c.state.Status = CAMPAIGN_HALTED
+		c.mu.Unlock()
+		if err := c.save(); err != nil {
+			return err
+		}
+		return ErrCampaignHalted
+	}
+
+	if err := c.save(); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("goplatform: firmware campaign: wave %d: %w", wave, errors.Join(errs...))
+	}
+
+	return nil
+}
+
+// upgrade sends the update command to device i, unless already sent, and
+// waits for the device to report the new version. The device fails as soon
+// as the command is reported failed. Permanent errors fail the device;
+// transient ones are retried until the timeout and then returned, leaving
+// the device to be resumed.
+func (c *FirmwareCampaign) upgrade(ctx context.Context, i int) error {
+	c.mu.Lock()
+	device := c.state.Devices[i]
+	spec := c.state.Spec
+	c.mu.Unlock()
+
+	if device.Status == CAMPAIGN_DEVICE_PENDING {
+		deadline := time.Now().Add(spec.Timeout)
+
+		var d Device
+		err := c.retry(ctx, deadline, spec.PollInterval, func() (err error) {
+			d, err = c.project.GetDevice(ctx, device.DeviceId)
+			return err
+		})
+		if err == nil && d.FirmwareVersion == spec.Version {
+			c.finish(i, nil)
+			return nil
+		}
+
+		var command Command
+		if err == nil {
+			err = c.retry(ctx, deadline, spec.PollInterval, func() (err error) {
+				command, err = d.UpdateFirmware(ctx, spec.Version)
+				return err
+			})
+		}
+		switch {
+		case ctx.Err() != nil:
+			return nil
+		case err != nil && isTransient(err):
+			return fmt.Errorf("device %s: %w", device.DeviceId, err)
+		case err != nil:
+			c.finish(i, err)
+			return nil
+		}
+
+		now := time.Now()
+		c.mu.Lock()
+		c.state.Devices[i].Status = CAMPAIGN_DEVICE_UPDATING
+		c.state.Devices[i].CommandId = command.Uuid
+		c.state.Devices[i].StartedAt = &now
+		device = c.state.Devices[i]
+		c.mu.Unlock()
+		c.save() //nolint:errcheck // Recorded in saveErr
+	}
+
+	deadline := device.StartedAt.Add(spec.Timeout)
+	for {
+		d, err := c.project.GetDevice(ctx, device.DeviceId)
+		switch {
+		case ctx.Err() != nil:
+			return nil
+		case err == nil && d.FirmwareVersion == spec.Version:
+			c.finish(i, nil)
+			return nil
+		case err != nil && !isTransient(err):
+			c.finish(i, err)
+			return nil
+		}
+
+		// The command status is only a shortcut, the version is what counts
+		if device.CommandId != "" {
+			command, err := c.project.GetCommand(ctx, device.CommandId)
+			if err == nil && command.Status == CMD_STATUS_FAILED {
+				c.finish(i, fmt.Errorf("command %s failed", device.CommandId))
+				return nil
+			}
+		}
+
+		if time.Now().After(deadline) {
+			if err != nil {
+				return fmt.Errorf("device %s: %w", device.DeviceId, err)
+			}
+			c.finish(i, fmt.Errorf("timeout waiting for firmware %s", spec.Version))
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(spec.PollInterval):
+		}
+	}
+}
+
+// retry calls fn until it succeeds, fails with a permanent error or deadline
+// passes, waiting interval after transient errors.
+func (c *FirmwareCampaign) retry(ctx context.Context, deadline time.Time, interval time.Duration, fn func() error) error {
+	for {
+		err := fn()
+		if err == nil || !isTransient(err) || ctx.Err() != nil || time.Now().After(deadline) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (c *FirmwareCampaign) finish(i int, err error) {
+	c.mu.Lock()
+	if err != nil {
+		c.state.Devices[i].Status = CAMPAIGN_DEVICE_FAILED
+		c.state.Devices[i].Error = err.Error()
+	} else {
+		c.state.Devices[i].Status = CAMPAIGN_DEVICE_SUCCEEDED
+	}
+	c.mu.Unlock()
+
+	c.save() //nolint:errcheck // Recorded in saveErr
+}
+
+// exceeded reports whether the share of failed devices, among the ones
+// done, exceeds the failure threshold.
+func (c *FirmwareCampaign) exceeded() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	failed, done := 0, 0
+	for _, d := range c.state.Devices {
+		switch d.Status {
+		case CAMPAIGN_DEVICE_FAILED:
+			failed++
+			done++
+		case CAMPAIGN_DEVICE_SUCCEEDED:
+			done++
+		}
+	}
+
+	return done > 0 && float64(failed)/float64(done) > c.state.Spec.FailureThreshold
+}
+
+// save persists the state. Once a save fails, the error is kept and returned
+// by every following save so that Run reports it.
+func (c *FirmwareCampaign) save() error {
+	c.saveMu.Lock()
+	defer c.saveMu.Unlock()
+
+	if c.saveErr != nil {
+		return c.saveErr
+	}
+
+	c.mu.Lock()
+	b, err := json.MarshalIndent(c.state, "", "  ")
+	c.mu.Unlock()
+	if err == nil {
+		err = writeFileSync(c.path, b)
+	}
+	if err != nil {
+		c.saveErr = fmt.Errorf("goplatform: firmware campaign %s: %w", c.path, err)
+	}
+
+	return c.saveErr
+}
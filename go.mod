@@ -5,6 +5,7 @@ go 1.23
 require (
 	github.com/google/uuid v1.6.0
 	github.com/h2non/gock v1.2.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require github.com/h2non/parth v0.0.0-20190131123155-b4df798d6542 // indirect
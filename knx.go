@@ -0,0 +1,74 @@
+package goplatform
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ApioIoT/goplatform/v2/knx"
+)
+
+// Validate checks that every property has a valid group address and
+// supported send and receive datapoint types.
+func (p DeviceTypeKnxProtocol) Validate() error {
+	names := make([]string, 0, len(p.Properties))
+	for name := range p.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		prop := p.Properties[name]
+		if _, err := knx.ParseGroupAddress(prop.Address); err != nil {
+			return fmt.Errorf("goplatform: knx: property %q: %w", name, err)
+		}
+		if prop.SendDPT != "" {
+			if _, err := knx.ParseDPT(prop.SendDPT); err != nil {
+				return fmt.Errorf("goplatform: knx: property %q: %w", name, err)
+			}
+		}
+		if prop.ReceiveDPT != "" {
+			if _, err := knx.ParseDPT(prop.ReceiveDPT); err != nil {
+				return fmt.Errorf("goplatform: knx: property %q: %w", name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// EncodeProperty encodes value with the send datapoint type of property and
+// returns the group address to write it to.
+func (p DeviceTypeKnxProtocol) EncodeProperty(property string, value any) (knx.GroupAddress, []byte, error) {
+	prop, ok := p.Properties[property]
+	if !ok {
+		return 0, nil, fmt.Errorf("goplatform: knx: property %q not found", property)
+	}
+
+	address, err := knx.ParseGroupAddress(prop.Address)
+	if err != nil {
+		return 0, nil, fmt.Errorf("goplatform: knx: property %q: %w", property, err)
+	}
+
+	data, err := knx.Encode(prop.SendDPT, value)
+	if err != nil {
+		return 0, nil, fmt.Errorf("goplatform: knx: property %q: %w", property, err)
+	}
+
+	return address, data, nil
+}
+
+// DecodeProperty decodes data received for property with its receive
+// datapoint type.
+func (p DeviceTypeKnxProtocol) DecodeProperty(property string, data []byte) (any, error) {
+	prop, ok := p.Properties[property]
+	if !ok {
+		return nil, fmt.Errorf("goplatform: knx: property %q not found", property)
+	}
+
+	value, err := knx.Decode(prop.ReceiveDPT, data)
+	if err != nil {
+		return nil, fmt.Errorf("goplatform: knx: property %q: %w", property, err)
+	}
+
+	return value, nil
+}
@@ -0,0 +1,70 @@
+// Package knx implements the KNX datapoint types and group addresses used by
+// the KNX protocol of Apio Platform device types.
+package knx
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// GroupAddress is a 16 bit KNX group address.
+type GroupAddress uint16
+
+// ParseGroupAddress parses a group address written in 3-level
+// ("main/middle/sub"), 2-level ("main/sub") or free ("n") format. The
+// broadcast address 0/0/0 is rejected.
+func ParseGroupAddress(s string) (GroupAddress, error) {
+	parts := strings.Split(strings.TrimSpace(s), "/")
+
+	levels := make([]int, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("knx: invalid group address %q", s)
+		}
+		levels[i] = n
+	}
+
+	var address int
+	switch len(levels) {
+	case 3:
+		if levels[0] > 31 || levels[1] > 7 || levels[2] > 255 {
+			return 0, fmt.Errorf("knx: group address %q out of range", s)
+		}
+		address = levels[0]<<11 | levels[1]<<8 | levels[2]
+	case 2:
+		if levels[0] > 31 || levels[1] > 2047 {
+			return 0, fmt.Errorf("knx: group address %q out of range", s)
+		}
+		address = levels[0]<<11 | levels[1]
+	case 1:
+		if levels[0] > 0xFFFF {
+			return 0, fmt.Errorf("knx: group address %q out of range", s)
+		}
+		address = levels[0]
+	default:
+		return 0, fmt.Errorf("knx: invalid group address %q", s)
+	}
+
+	if address == 0 {
+		return 0, fmt.Errorf("knx: group address %q is the broadcast address", s)
+	}
+
+	return GroupAddress(address), nil //nolint:gosec
+}
+
+// String returns the address in 3-level format.
+func (g GroupAddress) String() string {
+	return fmt.Sprintf("%d/%d/%d", g>>11, (g>>8)&0x07, g&0xFF)
+}
+
+// TwoLevel returns the address in 2-level format.
+func (g GroupAddress) TwoLevel() string {
+	return fmt.Sprintf("%d/%d", g>>11, g&0x07FF)
+}
+
+// Free returns the address in free format.
+func (g GroupAddress) Free() string {
+	return strconv.Itoa(int(g))
+}
@@ -0,0 +1,333 @@
+package knx
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DPT identifies a KNX datapoint type as main and sub number.
+type DPT struct {
+	Main int
+	Sub  int
+}
+
+// ParseDPT parses a datapoint type in the "1.001", "DPT1.001", "DPT-1" or
+// "DPST-1-1" formats. When the sub number is omitted it is zero.
+func ParseDPT(s string) (DPT, error) {
+	var zero DPT
+
+	str := strings.ToUpper(strings.TrimSpace(s))
+
+	var parts []string
+	switch {
+	case strings.HasPrefix(str, "DPST-"):
+		parts = strings.Split(strings.TrimPrefix(str, "DPST-"), "-")
+	case strings.HasPrefix(str, "DPT-"):
+		parts = strings.Split(strings.TrimPrefix(str, "DPT-"), "-")
+	default:
+		parts = strings.Split(strings.TrimPrefix(str, "DPT"), ".")
+	}
+
+	if len(parts) < 1 || len(parts) > 2 {
+		return zero, fmt.Errorf("knx: invalid datapoint type %q", s)
+	}
+
+	main, err := strconv.Atoi(parts[0])
+	if err != nil || main <= 0 {
+		return zero, fmt.Errorf("knx: invalid datapoint type %q", s)
+	}
+
+	sub := 0
+	if len(parts) == 2 {
+		sub, err = strconv.Atoi(parts[1])
+		if err != nil || sub < 0 {
+			return zero, fmt.Errorf("knx: invalid datapoint type %q", s)
+		}
+	}
+
+	dpt := DPT{Main: main, Sub: sub}
+	if !dpt.Supported() {
+		return zero, fmt.Errorf("knx: unsupported datapoint type %q", s)
+	}
+
+	return dpt, nil
+}
+
+// String returns the datapoint type in "main.sub" format.
+func (d DPT) String() string {
+	return fmt.Sprintf("%d.%03d", d.Main, d.Sub)
+}
+
+// Supported reports whether the codec can encode and decode d.
+func (d DPT) Supported() bool {
+	switch d.Main {
+	case 1, 5, 7, 8, 9, 14, 16:
+		return true
+	default:
+		return false
+	}
+}
+
+// Size returns the length in bytes of the encoded value. Types shorter than a
+// byte are encoded in one byte.
+func (d DPT) Size() int {
+	switch d.Main {
+	case 1, 5:
+		return 1
+	case 7, 8, 9:
+		return 2
+	case 14:
+		return 4
+	case 16:
+		return 14
+	default:
+		return 0
+	}
+}
+
+// Encode encodes value as the datapoint type dpt.
+func Encode(dpt string, value any) ([]byte, error) {
+	d, err := ParseDPT(dpt)
+	if err != nil {
+		return nil, err
+	}
+	return d.Encode(value)
+}
+
+// Decode decodes data as the datapoint type dpt.
+func Decode(dpt string, data []byte) (any, error) {
+	d, err := ParseDPT(dpt)
+	if err != nil {
+		return nil, err
+	}
+	return d.Decode(data)
+}
+
+// Encode encodes value as d. Booleans (1.x) are encoded in the lowest bit of
+// a single byte.
+func (d DPT) Encode(value any) ([]byte, error) {
+	switch d.Main {
+	case 1:
+		b, ok := toBool(value)
+		if !ok {
+			return nil, fmt.Errorf("knx: invalid value %v for DPT %s", value, d)
+		}
+		if b {
+			return []byte{1}, nil
+		}
+		return []byte{0}, nil
+	case 5:
+		f, ok := toFloat64(value)
+		if !ok {
+			return nil, fmt.Errorf("knx: invalid value %v for DPT %s", value, d)
+		}
+		switch d.Sub {
+		case 1:
+			f = f * 255 / 100
+		case 3:
+			f = f * 255 / 360
+		}
+		n := math.Round(f)
+		if n < 0 || n > math.MaxUint8 {
+			return nil, fmt.Errorf("knx: value %v out of range for DPT %s", value, d)
+		}
+		return []byte{byte(n)}, nil
+	case 7:
+		f, ok := toFloat64(value)
+		if !ok {
+			return nil, fmt.Errorf("knx: invalid value %v for DPT %s", value, d)
+		}
+		n := math.Round(f)
+		if n < 0 || n > math.MaxUint16 {
+			return nil, fmt.Errorf("knx: value %v out of range for DPT %s", value, d)
+		}
+		u := uint16(n)
+		return []byte{byte(u >> 8), byte(u)}, nil
+	case 8:
+		f, ok := toFloat64(value)
+		if !ok {
+			return nil, fmt.Errorf("knx: invalid value %v for DPT %s", value, d)
+		}
+		n := math.Round(f)
+		if n < math.MinInt16 || n > math.MaxInt16 {
+			return nil, fmt.Errorf("knx: value %v out of range for DPT %s", value, d)
+		}
+		u := uint16(int16(n))
+		return []byte{byte(u >> 8), byte(u)}, nil
+	case 9:
+		f, ok := toFloat64(value)
+		if !ok {
+			return nil, fmt.Errorf("knx: invalid value %v for DPT %s", value, d)
+		}
+		u, err := encodeFloat16(f)
+		if err != nil {
+			return nil, fmt.Errorf("knx: value %v out of range for DPT %s", value, d)
+		}
+		return []byte{byte(u >> 8), byte(u)}, nil
+	case 14:
+		f, ok := toFloat64(value)
+		if !ok {
+			return nil, fmt.Errorf("knx: invalid value %v for DPT %s", value, d)
+		}
+		if math.Abs(f) > math.MaxFloat32 {
+			return nil, fmt.Errorf("knx: value %v out of range for DPT %s", value, d)
+		}
+		u := math.Float32bits(float32(f))
+		return []byte{byte(u >> 24), byte(u >> 16), byte(u >> 8), byte(u)}, nil
+	case 16:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("knx: invalid value %v for DPT %s", value, d)
+		}
+		return encodeString(d, s)
+	default:
+		return nil, fmt.Errorf("knx: unsupported datapoint type %s", d)
+	}
+}
+
+// Decode decodes data as d. It returns a bool for 1.x, a float64 for 5.001,
+// 5.003, 9.x and 14.x, a uint8 for the other 5.x, a uint16 for 7.x, an int16
+// for 8.x and a string for 16.x.
+func (d DPT) Decode(data []byte) (any, error) {
+	if size := d.Size(); len(data) != size {
+		return nil, fmt.Errorf("knx: DPT %s expects %d bytes, got %d", d, size, len(data))
+	}
+
+	switch d.Main {
+	case 1:
+		return data[0]&0x01 != 0, nil
+	case 5:
+		switch d.Sub {
+		case 1:
+			return float64(data[0]) * 100 / 255, nil
+		case 3:
+			return float64(data[0]) * 360 / 255, nil
+		default:
+			return data[0], nil
+		}
+	case 7:
+		return uint16(data[0])<<8 | uint16(data[1]), nil
+	case 8:
+		return int16(uint16(data[0])<<8 | uint16(data[1])), nil //nolint:gosec
+	case 9:
+		return decodeFloat16(uint16(data[0])<<8 | uint16(data[1])), nil
+	case 14:
+		u := uint32(data[0])<<24 | uint32(data[1])<<16 | uint32(data[2])<<8 | uint32(data[3])
+		return float64(math.Float32frombits(u)), nil
+	case 16:
+		return decodeString(data), nil
+	default:
+		return nil, fmt.Errorf("knx: unsupported datapoint type %s", d)
+	}
+}
+
+// encodeFloat16 encodes f as the KNX 2-byte float: 0.01 * M * 2^E with a 12
+// bit two's complement mantissa and a 4 bit exponent.
+func encodeFloat16(f float64) (uint16, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("knx: value %v out of range", f)
+	}
+
+	m := math.Round(f * 100)
+	e := 0
+	for m < -2048 || m > 2047 {
+		e++
+		m = math.Round(f * 100 / math.Ldexp(1, e))
+	}
+	if e > 15 {
+		return 0, fmt.Errorf("knx: value %v out of range", f)
+	}
+
+	mantissa := int(m) & 0x0FFF
+	return uint16((mantissa&0x0800)<<4 | e<<11 | mantissa&0x07FF), nil //nolint:gosec
+}
+
+func decodeFloat16(u uint16) float64 {
+	m := int(u & 0x07FF)
+	if u&0x8000 != 0 {
+		m -= 2048
+	}
+	e := int(u>>11) & 0x0F
+	return float64(m) * math.Ldexp(1, e) / 100
+}
+
+// encodeString encodes s as a 14 byte, zero padded string. 16.000 only
+// allows ASCII while 16.001 allows ISO 8859-1.
+func encodeString(d DPT, s string) ([]byte, error) {
+	limit := rune(0x7F)
+	if d.Sub == 1 {
+		limit = 0xFF
+	}
+
+	res := make([]byte, 0, 14)
+	for _, r := range s {
+		if r > limit {
+			return nil, fmt.Errorf("knx: character %q not allowed in DPT %s", r, d)
+		}
+		res = append(res, byte(r))
+	}
+
+	if len(res) > 14 {
+		return nil, fmt.Errorf("knx: string %q too long for DPT %s", s, d)
+	}
+
+	return append(res, make([]byte, 14-len(res))...), nil
+}
+
+func decodeString(data []byte) string {
+	data = bytes.TrimRight(data, "\x00")
+
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
+
+func toFloat64(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+func toBool(value any) (bool, bool) {
+	switch v := value.(type) {
+	case bool:
+		return v, true
+	default:
+		f, ok := toFloat64(value)
+		return f != 0, ok
+	}
+}
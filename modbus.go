@@ -0,0 +1,367 @@
+package goplatform
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+const (
+	MODBUS_FC_READ_COILS              = 1
+	MODBUS_FC_READ_DISCRETE_INPUTS    = 2
+	MODBUS_FC_READ_HOLDING_REGISTERS  = 3
+	MODBUS_FC_READ_INPUT_REGISTERS    = 4
+	MODBUS_FC_WRITE_SINGLE_COIL       = 5
+	MODBUS_FC_WRITE_SINGLE_REGISTER   = 6
+	MODBUS_FC_WRITE_MULTIPLE_COILS    = 15
+	MODBUS_FC_WRITE_MULTIPLE_REGISTER = 16
+)
+
+const (
+	MODBUS_ENDIANNESS_ABCD = "ABCD"
+	MODBUS_ENDIANNESS_BADC = "BADC"
+	MODBUS_ENDIANNESS_CDAB = "CDAB"
+	MODBUS_ENDIANNESS_DCBA = "DCBA"
+)
+
+const (
+	MODBUS_TYPE_INT    = "int"
+	MODBUS_TYPE_UINT   = "uint"
+	MODBUS_TYPE_FLOAT  = "float"
+	MODBUS_TYPE_DOUBLE = "double"
+)
+
+// ModbusWriteRequest is a single Modbus write ready to be sent on the wire.
+type ModbusWriteRequest struct {
+	Function int      `json:"function"`
+	Address  uint16   `json:"address"`
+	Words    []uint16 `json:"words"`
+}
+
+// FindRegister returns the register that holds the given property.
+func (p DeviceTypeModbusProtocol) FindRegister(property string) (DeviceTypeModbusRegister, bool) {
+	for _, register := range p.Registers {
+		for _, prop := range register.Properties {
+			if prop.Name == property {
+				return register, true
+			}
+		}
+	}
+
+	var zero DeviceTypeModbusRegister
+	return zero, false
+}
+
+// EncodeRegisterWrite encodes value into the raw words to be written for
+// property, honoring the register endianness, size, type and scale factor.
+func EncodeRegisterWrite(protocol DeviceTypeModbusProtocol, property string, value any) (ModbusWriteRequest, error) {
+	var zero ModbusWriteRequest
+
+	register, ok := protocol.FindRegister(property)
+	if !ok {
+		return zero, fmt.Errorf("goplatform: modbus: property %q not found", property)
+	}
+
+	if !register.Write {
+		return zero, fmt.Errorf("goplatform: modbus: register %d is read-only", register.Register)
+	}
+
+	words := registerWords(register)
+	function := register.ModbusFunctionWrite
+
+	switch function {
+	case MODBUS_FC_WRITE_SINGLE_COIL, MODBUS_FC_WRITE_MULTIPLE_COILS:
+		b, ok := toBool(value)
+		if !ok {
+			return zero, fmt.Errorf("goplatform: modbus: invalid value %v for coil %d", value, register.Register)
+		}
+
+		// A single coil is written as 0xFF00 for ON, multiple coils are
+		// packed one per bit
+		raw := uint16(0x0000)
+		switch {
+		case b && function == MODBUS_FC_WRITE_SINGLE_COIL:
+			raw = 0xFF00
+		case b:
+			raw = 0x0001
+		}
+		return ModbusWriteRequest{Function: function, Address: register.Register, Words: []uint16{raw}}, nil
+	case 0:
+		function = MODBUS_FC_WRITE_MULTIPLE_REGISTER
+		if words == 1 {
+			function = MODBUS_FC_WRITE_SINGLE_REGISTER
+		}
+	case MODBUS_FC_WRITE_SINGLE_REGISTER:
+		if words != 1 {
+			return zero, fmt.Errorf("goplatform: modbus: function %d cannot write %d words", function, words)
+		}
+	case MODBUS_FC_WRITE_MULTIPLE_REGISTER:
+		// Intentionally empty
+	default:
+		return zero, fmt.Errorf("goplatform: modbus: unsupported write function %d", function)
+	}
+
+	if register.BitwiseReading {
+		return zero, fmt.Errorf("goplatform: modbus: register %d is bitwise, writing a single bit is not supported", register.Register)
+	}
+
+	raw, err := encodeRegisterValue(register, words, value)
+	if err != nil {
+		return zero, err
+	}
+
+	ordered, err := applyEndianness(protocol.Endianness, splitWords(raw, words))
+	if err != nil {
+		return zero, err
+	}
+
+	return ModbusWriteRequest{Function: function, Address: register.Register, Words: ordered}, nil
+}
+
+func registerWords(register DeviceTypeModbusRegister) int {
+	if register.Words == 0 {
+		return 1
+	}
+	return int(register.Words)
+}
+
+func encodeRegisterValue(register DeviceTypeModbusRegister, words int, value any) (uint64, error) {
+	if words > 4 {
+		return 0, fmt.Errorf("goplatform: modbus: register %d has unsupported size of %d words", register.Register, words)
+	}
+
+	f, ok := toFloat64(value)
+	if !ok {
+		return 0, fmt.Errorf("goplatform: modbus: invalid value %v for register %d", value, register.Register)
+	}
+
+	if register.ScaleFactor != nil {
+		if *register.ScaleFactor == 0 {
+			return 0, fmt.Errorf("goplatform: modbus: register %d has a zero scale factor", register.Register)
+		}
+		f = f / *register.ScaleFactor
+	}
+
+	bits := uint(words * 16)
+
+	switch register.Type {
+	case MODBUS_TYPE_INT:
+		n := math.Round(f)
+		limit := math.Ldexp(1, int(bits)-1)
+		if n < -limit || n >= limit {
+			return 0, fmt.Errorf("goplatform: modbus: value %v overflows register %d", value, register.Register)
+		}
+		return uint64(int64(n)) & mask(bits), nil
+	case MODBUS_TYPE_UINT, "":
+		n := math.Round(f)
+		if n < 0 || n >= math.Ldexp(1, int(bits)) {
+			return 0, fmt.Errorf("goplatform: modbus: value %v overflows register %d", value, register.Register)
+		}
+		return uint64(n), nil
+	case MODBUS_TYPE_FLOAT, MODBUS_TYPE_DOUBLE:
+		switch words {
+		case 2:
+			return uint64(math.Float32bits(float32(f))), nil
+		case 4:
+			return math.Float64bits(f), nil
+		default:
+			return 0, fmt.Errorf("goplatform: modbus: register %d cannot hold a float in %d words", register.Register, words)
+		}
+	default:
+		return 0, fmt.Errorf("goplatform: modbus: unsupported register type %q", register.Type)
+	}
+}
+
+func mask(bits uint) uint64 {
+	if bits >= 64 {
+		return math.MaxUint64
+	}
+	return (1 << bits) - 1
+}
+
+// splitWords splits raw into words, most significant word first.
+func splitWords(raw uint64, words int) []uint16 {
+	res := make([]uint16, words)
+	for i := 0; i < words; i++ {
+		res[words-1-i] = uint16(raw >> (16 * i)) //nolint:gosec
+	}
+	return res
+}
+
+// applyEndianness reorders big-endian words according to endianness. The
+// transformation is its own inverse, so it serves both reads and writes.
+func applyEndianness(endianness string, words []uint16) ([]uint16, error) {
+	res := make([]uint16, len(words))
+	copy(res, words)
+
+	var swapWords, swapBytes bool
+	switch endianness {
+	case MODBUS_ENDIANNESS_ABCD, "":
+		// Intentionally empty
+	case MODBUS_ENDIANNESS_BADC:
+		swapBytes = true
+	case MODBUS_ENDIANNESS_CDAB:
+		swapWords = true
+	case MODBUS_ENDIANNESS_DCBA:
+		swapWords = true
+		swapBytes = true
+	default:
+		return nil, fmt.Errorf("goplatform: modbus: unsupported endianness %q", endianness)
+	}
+
+	if swapWords {
+		for i, j := 0, len(res)-1; i < j; i, j = i+1, j-1 {
+			res[i], res[j] = res[j], res[i]
+		}
+	}
+	if swapBytes {
+		for i, w := range res {
+			res[i] = w<<8 | w>>8
+		}
+	}
+
+	return res, nil
+}
+
+func toFloat64(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+func toBool(value any) (bool, bool) {
+	switch v := value.(type) {
+	case bool:
+		return v, true
+	case string:
+		b, err := strconv.ParseBool(v)
+		return b, err == nil
+	default:
+		f, ok := toFloat64(value)
+		return f != 0, ok
+	}
+}
+
+// DecodeRegisterRead decodes the raw words read from register into the values
+// of its properties, honoring endianness, size, type and scale factor. Coils
+// and discrete inputs are expected as one word per bit.
+func DecodeRegisterRead(endianness string, register DeviceTypeModbusRegister, words []uint16) (map[string]any, error) {
+	res := map[string]any{}
+
+	if register.ModbusFunctionRead == MODBUS_FC_READ_COILS || register.ModbusFunctionRead == MODBUS_FC_READ_DISCRETE_INPUTS {
+		if len(words) < 1 {
+			return nil, fmt.Errorf("goplatform: modbus: missing data for register %d", register.Register)
+		}
+		for _, prop := range register.Properties {
+			res[prop.Name] = words[0] != 0
+		}
+		return res, nil
+	}
+
+	size := registerWords(register)
+	if len(words) != size {
+		return nil, fmt.Errorf("goplatform: modbus: register %d expects %d words, got %d", register.Register, size, len(words))
+	}
+	if size > 4 {
+		return nil, fmt.Errorf("goplatform: modbus: register %d has unsupported size of %d words", register.Register, size)
+	}
+
+	ordered, err := applyEndianness(endianness, words)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw uint64
+	for _, w := range ordered {
+		raw = raw<<16 | uint64(w)
+	}
+
+	if register.BitwiseReading {
+		for _, prop := range register.Properties {
+			if prop.Index < 0 || prop.Index >= size*16 {
+				return nil, fmt.Errorf("goplatform: modbus: bit %d out of range for register %d", prop.Index, register.Register)
+			}
+			res[prop.Name] = raw&(1<<prop.Index) != 0
+		}
+		return res, nil
+	}
+
+	value, err := decodeRegisterValue(register, size, raw)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, prop := range register.Properties {
+		res[prop.Name] = value
+	}
+
+	return res, nil
+}
+
+func decodeRegisterValue(register DeviceTypeModbusRegister, words int, raw uint64) (any, error) {
+	bits := uint(words * 16)
+
+	var value any
+	switch register.Type {
+	case MODBUS_TYPE_INT:
+		shift := 64 - bits
+		value = int64(raw<<shift) >> shift //nolint:gosec
+	case MODBUS_TYPE_UINT, "":
+		value = raw
+	case MODBUS_TYPE_FLOAT, MODBUS_TYPE_DOUBLE:
+		switch words {
+		case 2:
+			value = float64(math.Float32frombits(uint32(raw))) //nolint:gosec
+		case 4:
+			value = math.Float64frombits(raw)
+		default:
+			return nil, fmt.Errorf("goplatform: modbus: register %d cannot hold a float in %d words", register.Register, words)
+		}
+	default:
+		return nil, fmt.Errorf("goplatform: modbus: unsupported register type %q", register.Type)
+	}
+
+	if register.ScaleFactor != nil {
+		f, _ := toFloat64(value)
+		return f * *register.ScaleFactor, nil
+	}
+
+	return value, nil
+}
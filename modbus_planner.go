@@ -0,0 +1,165 @@
+package goplatform
+
+import (
+	"fmt"
+	"maps"
+	"sort"
+)
+
+const (
+	MODBUS_MAX_READ_REGISTERS = 125
+	MODBUS_MAX_READ_BITS      = 2000
+)
+
+// ModbusReadPlanOptions tunes how PlanModbusReads merges registers.
+type ModbusReadPlanOptions struct {
+	// MaxGap is the maximum number of unused addresses allowed between two
+	// registers merged in the same request.
+	MaxGap int
+	// MaxLength is the maximum quantity of a single request. It defaults to,
+	// and is capped at, the protocol limit for the read function.
+	MaxLength int
+}
+
+// ModbusReadRequest is a single Modbus read covering one or more registers.
+type ModbusReadRequest struct {
+	Function  int                        `json:"function"`
+	Address   uint16                     `json:"address"`
+	Quantity  uint16                     `json:"quantity"`
+	Registers []DeviceTypeModbusRegister `json:"registers"`
+}
+
+// PlanModbusReads groups the readable registers of protocol by read function
+// and merges contiguous or near-contiguous address ranges, producing the
+// fewest requests allowed by options.
+func PlanModbusReads(protocol DeviceTypeModbusProtocol, options ...ModbusReadPlanOptions) ([]ModbusReadRequest, error) {
+	var opts ModbusReadPlanOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+
+	if opts.MaxGap < 0 {
+		return nil, fmt.Errorf("goplatform: modbus: invalid max gap %d", opts.MaxGap)
+	}
+
+	byFunction := map[int][]DeviceTypeModbusRegister{}
+	for _, register := range protocol.Registers {
+		if !register.Read {
+			continue
+		}
+		if !isModbusReadFunction(register.ModbusFunctionRead) {
+			return nil, fmt.Errorf("goplatform: modbus: unsupported read function %d for register %d", register.ModbusFunctionRead, register.Register)
+		}
+		byFunction[register.ModbusFunctionRead] = append(byFunction[register.ModbusFunctionRead], register)
+	}
+
+	functions := make([]int, 0, len(byFunction))
+	for function := range byFunction {
+		functions = append(functions, function)
+	}
+	sort.Ints(functions)
+
+	var plan []ModbusReadRequest
+	for _, function := range functions {
+		registers := byFunction[function]
+		sort.SliceStable(registers, func(i, j int) bool {
+			return registers[i].Register < registers[j].Register
+		})
+
+		maxLength := MODBUS_MAX_READ_REGISTERS
+		if isModbusBitFunction(function) {
+			maxLength = MODBUS_MAX_READ_BITS
+		}
+		if opts.MaxLength > 0 && opts.MaxLength < maxLength {
+			maxLength = opts.MaxLength
+		}
+
+		current := -1
+		var start, end int
+		for _, register := range registers {
+			from := int(register.Register)
+			to := from + readQuantity(register)
+
+			if to-from > maxLength {
+				return nil, fmt.Errorf("goplatform: modbus: register %d exceeds max read length of %d", register.Register, maxLength)
+			}
+			if to > 0x10000 {
+				return nil, fmt.Errorf("goplatform: modbus: register %d exceeds address space", register.Register)
+			}
+
+			if current >= 0 && from-end <= opts.MaxGap && max(end, to)-start <= maxLength {
+				end = max(end, to)
+				plan[current].Quantity = uint16(end - start) //nolint:gosec
+				plan[current].Registers = append(plan[current].Registers, register)
+				continue
+			}
+
+			plan = append(plan, ModbusReadRequest{
+				Function:  function,
+				Address:   register.Register,
+				Quantity:  uint16(to - from), //nolint:gosec
+				Registers: []DeviceTypeModbusRegister{register},
+			})
+			current = len(plan) - 1
+			start, end = from, to
+		}
+	}
+
+	return plan, nil
+}
+
+// Split maps the response of the request back to the raw words of each of its
+// registers, in the same order as r.Registers.
+func (r ModbusReadRequest) Split(response []uint16) ([][]uint16, error) {
+	if len(response) < int(r.Quantity) {
+		return nil, fmt.Errorf("goplatform: modbus: expected %d words from address %d, got %d", r.Quantity, r.Address, len(response))
+	}
+
+	res := make([][]uint16, len(r.Registers))
+	for i, register := range r.Registers {
+		offset := int(register.Register) - int(r.Address)
+		res[i] = response[offset : offset+readQuantity(register)]
+	}
+
+	return res, nil
+}
+
+// Decode maps the response of the request back to the property values of
+// each of its registers.
+func (r ModbusReadRequest) Decode(endianness string, response []uint16) (map[string]any, error) {
+	words, err := r.Split(response)
+	if err != nil {
+		return nil, err
+	}
+
+	res := map[string]any{}
+	for i, register := range r.Registers {
+		values, err := DecodeRegisterRead(endianness, register, words[i])
+		if err != nil {
+			return nil, err
+		}
+		maps.Copy(res, values)
+	}
+
+	return res, nil
+}
+
+func readQuantity(register DeviceTypeModbusRegister) int {
+	if isModbusBitFunction(register.ModbusFunctionRead) {
+		return 1
+	}
+	return registerWords(register)
+}
+
+func isModbusReadFunction(function int) bool {
+	switch function {
+	case MODBUS_FC_READ_COILS, MODBUS_FC_READ_DISCRETE_INPUTS, MODBUS_FC_READ_HOLDING_REGISTERS, MODBUS_FC_READ_INPUT_REGISTERS:
+		return true
+	default:
+		return false
+	}
+}
+
+func isModbusBitFunction(function int) bool {
+	return function == MODBUS_FC_READ_COILS || function == MODBUS_FC_READ_DISCRETE_INPUTS
+}
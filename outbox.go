@@ -0,0 +1,390 @@
+package goplatform
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type OutboxItemKind string
+
+const (
+	OUTBOX_EVENT   OutboxItemKind = "event"
+	OUTBOX_COMMAND OutboxItemKind = "command"
+)
+
+type OutboxOptions struct {
+	// RetryInterval is the initial delay before replaying after a failure,
+	// doubled up to MaxRetryInterval. Defaults to 5s and 5m.
+	RetryInterval    time.Duration
+	MaxRetryInterval time.Duration
+	// OnError is called with replay failures. Items rejected by the platform
+	// are dropped, since retrying them cannot succeed.
+	OnError func(error)
+}
+
+type outboxItem struct {
+	Kind       OutboxItemKind  `json:"kind"`
+	Uuid       string          `json:"uuid"`
+	EnqueuedAt time.Time       `json:"enqueuedAt"`
+	Body       json.RawMessage `json:"body"`
+	file       string
+}
+
+// Outbox is a disk-backed queue of writes to a project. Events and commands
+// are persisted before being sent and replayed in order, surviving restarts,
+// until the platform accepts them. Items are deduplicated by Uuid while
+// queued; an item may be sent twice if the process stops right after sending
+// it.
+//
+// Measures are not queued: the client has no call writing them to the
+// platform, so there is nothing to replay them through.
+type Outbox struct {
+	project Project
+	dir     string
+	options OutboxOptions
+
+	mu    sync.Mutex
+	items []outboxItem
+	uuids map[string]bool
+	seq   uint64
+
+	replay sync.Mutex
+	wake   chan struct{}
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// OpenOutbox opens, or creates, the outbox stored in dir and starts replaying
+// its items to project.
+func OpenOutbox(dir string, project Project, options ...OutboxOptions) (*Outbox, error) {
+	var _options OutboxOptions
+	if len(options) > 0 {
+		_options = options[0]
+	}
+
+	if _options.RetryInterval <= 0 {
+		_options.RetryInterval = 5 * time.Second
+	}
+	if _options.MaxRetryInterval < _options.RetryInterval {
+		_options.MaxRetryInterval = max(5*time.Minute, _options.RetryInterval)
+	}
+
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+
+	o := &Outbox{
+		project: project,
+		dir:     dir,
+		options: _options,
+		uuids:   map[string]bool{},
+		wake:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	if err := o.load(); err != nil {
+		return nil, err
+	}
+
+	go o.run()
+	o.notify()
+
+	return o, nil
+}
+
+func (o *Outbox) load() error {
+	entries, err := os.ReadDir(o.dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || filepath.Ext(name) != ".json" {
+			continue
+		}
+
+		b, err := os.ReadFile(filepath.Join(o.dir, name)) //nolint:gosec
+		if err != nil {
+			return err
+		}
+
+		var item outboxItem
+		if err := json.Unmarshal(b, &item); err != nil {
+			return fmt.Errorf("goplatform: outbox: %s: %w", name, err)
+		}
+		item.file = name
+
+		if seq, err := strconv.ParseUint(strings.TrimSuffix(name, ".json"), 10, 64); err == nil && seq > o.seq {
+			o.seq = seq
+		}
+
+		o.items = append(o.items, item)
+		o.uuids[item.Uuid] = true
+	}
+
+	sort.Slice(o.items, func(i, j int) bool {
+		return o.items[i].file < o.items[j].file
+	})
+
+	return nil
+}
+
+// CreateEvent queues event, assigning it a Uuid if missing.
+func (o *Outbox) CreateEvent(event Event) error {
+	event.ProjectID = o.project.Uuid
+	if event.Uuid == "" {
+		event.Uuid = uuid.NewString()
+	}
+	return o.enqueue(OUTBOX_EVENT, event.Uuid, event)
+}
+
+// SendCommand queues command, as made by CommandRequest.MakeCommand.
+func (o *Outbox) SendCommand(command Command) error {
+	if command.Uuid == "" {
+		return errors.New("goplatform: outbox: command without uuid")
+	}
+	return o.enqueue(OUTBOX_COMMAND, command.Uuid, command)
+}
+
+func (o *Outbox) enqueue(kind OutboxItemKind, id string, value any) error {
+	body, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.uuids[id] {
+		return nil
+	}
+
+	o.seq++
+	item := outboxItem{
+		Kind:       kind,
+		Uuid:       id,
+		EnqueuedAt: time.Now(),
+		Body:       body,
+		file:       fmt.Sprintf("%020d.json", o.seq),
+	}
+
+	b, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	if err := writeFileSync(filepath.Join(o.dir, item.file), b); err != nil {
+		return err
+	}
+
+	o.items = append(o.items, item)
+	o.uuids[id] = true
+	o.notify()
+
+	return nil
+}
+
+// Depth returns the number of queued items.
+func (o *Outbox) Depth() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return len(o.items)
+}
+
+// OldestAge returns how long the oldest queued item has been waiting at now,
+// 0 when the outbox is empty.
+func (o *Outbox) OldestAge(now time.Time) time.Duration {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if len(o.items) == 0 {
+		return 0
+	}
+	return now.Sub(o.items[0].EnqueuedAt)
+}
+
+// Flush replays the queued items in order, stopping at the first one that
+// fails with a transient error.
+func (o *Outbox) Flush(ctx context.Context) error {
+	o.replay.Lock()
+	defer o.replay.Unlock()
+
+	for {
+		o.mu.Lock()
+		if len(o.items) == 0 {
+			o.mu.Unlock()
+			return nil
+		}
+		item := o.items[0]
+		o.mu.Unlock()
+
+		err := o.send(ctx, item)
+		if err != nil && isTransient(err) {
+			return err
+		}
+		if err != nil && o.options.OnError != nil {
+			o.options.OnError(fmt.Errorf("goplatform: outbox: dropped %s %s: %w", item.Kind, item.Uuid, err))
+		}
+
+		if err := os.Remove(filepath.Join(o.dir, item.file)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+
+		o.mu.Lock()
+		o.items = o.items[1:]
+		delete(o.uuids, item.Uuid)
+		o.mu.Unlock()
+	}
+}
+
+// Close stops the replay. Queued items stay on disk for the next
+// OpenOutbox.
+func (o *Outbox) Close() error {
+	select {
+	case <-o.stop:
+	default:
+		close(o.stop)
+	}
+	<-o.done
+	return nil
+}
+
+func (o *Outbox) run() {
+	defer close(o.done)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-o.stop
+		cancel()
+	}()
+
+	delay := o.options.RetryInterval
+	var retry <-chan time.Time
+
+	for {
+		select {
+		case <-o.stop:
+			return
+		case <-o.wake:
+		case <-retry:
+		}
+
+		if err := o.Flush(ctx); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if o.options.OnError != nil {
+				o.options.OnError(err)
+			}
+			retry = time.After(delay)
+			delay = min(2*delay, o.options.MaxRetryInterval)
+			continue
+		}
+
+		retry = nil
+		delay = o.options.RetryInterval
+	}
+}
+
+func (o *Outbox) notify() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (o *Outbox) send(ctx context.Context, item outboxItem) error {
+	switch item.Kind {
+	case OUTBOX_EVENT:
+		var event Event
+		if err := json.Unmarshal(item.Body, &event); err != nil {
+			return err
+		}
+		return o.project.CreateEvent(ctx, event)
+	case OUTBOX_COMMAND:
+		var command Command
+		if err := json.Unmarshal(item.Body, &command); err != nil {
+			return err
+		}
+		return o.project.SendCommand(ctx, command)
+	default:
+		return fmt.Errorf("unknown kind %q", item.Kind)
+	}
+}
+
+// isTransient reports whether err may go away by retrying: network errors,
+// timeouts and server errors.
+func isTransient(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return retryableStatus(apiErr.StatusCode)
+	}
+
+	var netErr net.Error
+	var opErr *net.OpError
+	return errors.As(err, &netErr) || errors.As(err, &opErr) ||
+		errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled)
+}
+
+// writeFileSync writes data to name atomically and durably.
+func writeFileSync(name string, data []byte) error {
+	tmp := name + ".tmp"
+
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600) //nolint:gosec
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close() //nolint:errcheck
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close() //nolint:errcheck
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, name); err != nil {
+		return err
+	}
+
+	return syncDir(filepath.Dir(name))
+}
+
+// syncDir flushes the entries of dir, making renames and removals in it
+// durable. Directories cannot be synced on Windows.
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+
+	d, err := os.Open(dir) //nolint:gosec
+	if err != nil {
+		return err
+	}
+	if err := d.Sync(); err != nil {
+		d.Close() //nolint:errcheck
+		return err
+	}
+
+	return d.Close()
+}
@@ -0,0 +1,655 @@
+package goplatform
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type PlanAction string
+
+const (
+	PLAN_CREATE PlanAction = "create"
+	PLAN_UPDATE PlanAction = "update"
+	PLAN_DELETE PlanAction = "delete"
+)
+
+type ResourceKind string
+
+const (
+	RESOURCE_DEVICE_TYPE ResourceKind = "deviceType"
+	RESOURCE_NODE        ResourceKind = "node"
+	RESOURCE_DEVICE      ResourceKind = "device"
+	RESOURCE_RULE        ResourceKind = "rule"
+)
+
+// resourceOrder is the order in which resources are created and updated;
+// deletes happen in reverse.
+var resourceOrder = []ResourceKind{RESOURCE_DEVICE_TYPE, RESOURCE_NODE, RESOURCE_DEVICE, RESOURCE_RULE}
+
+var resourcePaths = map[ResourceKind]string{
+	RESOURCE_DEVICE_TYPE: "devicetypes",
+	RESOURCE_NODE:        "nodes",
+	RESOURCE_DEVICE:      "devices",
+	RESOURCE_RULE:        "rules",
+}
+
+// serverFields are managed by the platform and ignored when planning.
+var serverFields = map[ResourceKind][]string{
+	RESOURCE_DEVICE_TYPE: {"uuid", "projectId", "createdAt", "updatedAt", "_id", "__v"},
+	RESOURCE_NODE: {"uuid", "projectId", "createdAt", "updatedAt", "_id", "__v",
+		"connectivityStatus", "lastConnectionAt", "lastCommunicationAt", "lastDisconnectionAt"},
+	RESOURCE_DEVICE: {"uuid", "projectId", "createdAt", "updatedAt", "_id", "__v",
+		"connectivityStatus", "lastConnectionAt", "lastCommunicationAt", "lastDisconnectionAt", "lastActivityAt",
+		"state", "stateUpdatedAt", "deviceType"},
+	RESOURCE_RULE: {"uuid", "projectId", "createdAt", "updatedAt", "_id", "__v"},
+}
+
+// ProjectState is the desired state of the resources of a project. Devices
+// may reference device types and nodes, and rules may reference devices, by
+// name instead of UUID: references are resolved when the plan is applied.
+//
+// Only the fields written in the parsed document are compared with the
+// project. For states built in code, fields left to their zero value are not
+// compared.
+type ProjectState struct {
+	DeviceTypes []DeviceType `json:"deviceTypes,omitempty"`
+	Nodes       []Node       `json:"nodes,omitempty"`
+	Devices     []Device     `json:"devices,omitempty"`
+	Rules       []Rule       `json:"rules,omitempty"`
+	// documents are the resources as written in the parsed document, by
+	// kind and name.
+	documents map[ResourceKind]map[string]map[string]any
+}
+
+// ParseProjectState decodes a desired-state document written in YAML or
+// JSON.
+func ParseProjectState(data []byte) (ProjectState, error) {
+	var zero ProjectState
+
+	var doc map[string]any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return zero, err
+	}
+
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return zero, err
+	}
+
+	var state ProjectState
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&state); err != nil {
+		return zero, err
+	}
+
+	var errs []error
+	for i, rule := range state.Rules {
+		if err := rule.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("rules[%d]: %w", i, err))
+		}
+	}
+	if len(errs) > 0 {
+		return zero, errors.Join(errs...)
+	}
+
+	var written struct {
+		DeviceTypes []map[string]any `json:"deviceTypes"`
+		Nodes       []map[string]any `json:"nodes"`
+		Devices     []map[string]any `json:"devices"`
+		Rules       []map[string]any `json:"rules"`
+	}
+	if err := json.Unmarshal(b, &written); err != nil {
+		return zero, err
+	}
+
+	state.documents = map[ResourceKind]map[string]map[string]any{}
+	for kind, docs := range map[ResourceKind][]map[string]any{
+		RESOURCE_DEVICE_TYPE: written.DeviceTypes,
+		RESOURCE_NODE:        written.Nodes,
+		RESOURCE_DEVICE:      written.Devices,
+		RESOURCE_RULE:        written.Rules,
+	} {
+		state.documents[kind] = map[string]map[string]any{}
+		for _, doc := range docs {
+			name, _ := doc["name"].(string)
+			state.documents[kind][name] = doc
+		}
+	}
+
+	return state, nil
+}
+
+// written returns the fields of doc set in the desired state: the fields
+// present in the parsed document or, for resources built in code, the
+// fields not left to their zero value.
+func (s ProjectState) written(kind ResourceKind, doc map[string]any) map[string]any {
+	name, _ := doc["name"].(string)
+	if raw, ok := s.documents[kind][name]; ok {
+		return maskDocument(doc, raw)
+	}
+	return pruneDocument(doc)
+}
+
+// PlanDiff is a field changed by an update.
+type PlanDiff struct {
+	Path string `json:"path"`
+	Old  any    `json:"old"`
+	New  any    `json:"new"`
+}
+
+// PlanChange is a single create, update or delete of a resource. Document
+// is the resource sent by creates and updates, so a plan saved as JSON can be
+// applied later.
+type PlanChange struct {
+	Action   PlanAction     `json:"action"`
+	Kind     ResourceKind   `json:"kind"`
+	Name     string         `json:"name"`
+	Uuid     string         `json:"uuid,omitempty"`
+	Diffs    []PlanDiff     `json:"diffs,omitempty"`
+	Document map[string]any `json:"document,omitempty"`
+}
+
+type PlanOptions struct {
+	// Prune deletes the resources missing from desired, for the kinds listed
+	// in it.
+	Prune bool
+}
+
+// Plan is the ordered list of changes reconciling a project with its desired
+// state.
+type Plan struct {
+	Changes []PlanChange `json:"changes"`
+}
+
+// ApplyFailure is a change that could not be applied.
+type ApplyFailure struct {
+	Change PlanChange
+	Err    error
+}
+
+// ApplyResult reports the changes applied and the ones that failed.
+type ApplyResult struct {
+	Applied []PlanChange
+	Failed  []ApplyFailure
+}
+
+// Plan compares desired with the current resources of the project and
+// returns the changes needed to reconcile them. Resources are matched by
+// UUID when set, by name otherwise. Only the kinds listed in desired are
+// managed, even with an empty list; resources of those kinds missing from
+// desired are deleted when Prune is set.
+func (p Project) Plan(ctx context.Context, desired ProjectState, options ...PlanOptions) (Plan, error) {
+	var plan Plan
+
+	_options := PlanOptions{}
+	if len(options) > 0 {
+		_options = options[0]
+	}
+
+	deviceTypes, err := p.GetDeviceTypes(ctx)
+	if err != nil {
+		return plan, err
+	}
+	nodes, err := p.GetNodes(ctx)
+	if err != nil {
+		return plan, err
+	}
+	devices, err := p.GetDevices(ctx)
+	if err != nil {
+		return plan, err
+	}
+	rules, err := p.GetRules(ctx)
+	if err != nil {
+		return plan, err
+	}
+
+	managed := map[ResourceKind]bool{
+		RESOURCE_DEVICE_TYPE: desired.DeviceTypes != nil,
+		RESOURCE_NODE:        desired.Nodes != nil,
+		RESOURCE_DEVICE:      desired.Devices != nil,
+		RESOURCE_RULE:        desired.Rules != nil,
+	}
+
+	actual := map[ResourceKind][]map[string]any{}
+	wanted := map[ResourceKind][]map[string]any{}
+	for kind, pair := range map[ResourceKind][2]any{
+		RESOURCE_DEVICE_TYPE: {deviceTypes, desired.DeviceTypes},
+		RESOURCE_NODE:        {nodes, desired.Nodes},
+		RESOURCE_DEVICE:      {devices, desired.Devices},
+		RESOURCE_RULE:        {rules, desired.Rules},
+	} {
+		if actual[kind], err = toDocuments(pair[0]); err != nil {
+			return plan, err
+		}
+		if wanted[kind], err = toDocuments(pair[1]); err != nil {
+			return plan, err
+		}
+		for i, doc := range wanted[kind] {
+			wanted[kind][i] = desired.written(kind, doc)
+		}
+	}
+
+	names := map[ResourceKind]map[string]string{}
+	for kind, docs := range actual {
+		names[kind] = map[string]string{}
+		for _, doc := range docs {
+			name, _ := doc["name"].(string)
+			uuid, _ := doc["uuid"].(string)
+			names[kind][name] = uuid
+		}
+	}
+
+	var deletes []PlanChange
+	for _, kind := range resourceOrder {
+		if !managed[kind] {
+			continue
+		}
+		changes, removed, err := planResources(kind, actual[kind], wanted[kind], names)
+		if err != nil {
+			return plan, err
+		}
+		plan.Changes = append(plan.Changes, changes...)
+		if _options.Prune {
+			deletes = append(removed, deletes...)
+		}
+	}
+	plan.Changes = append(plan.Changes, deletes...)
+
+	return plan, nil
+}
+
+func planResources(kind ResourceKind, actual, wanted []map[string]any, names map[ResourceKind]map[string]string) ([]PlanChange, []PlanChange, error) {
+	byUuid := map[string]map[string]any{}
+	byName := map[string]map[string]any{}
+	for _, doc := range actual {
+		uuid, _ := doc["uuid"].(string)
+		name, _ := doc["name"].(string)
+		if _, ok := byName[name]; ok {
+			return nil, nil, fmt.Errorf("goplatform: plan: duplicate %s %q in project", kind, name)
+		}
+		byUuid[uuid] = doc
+		byName[name] = doc
+	}
+
+	var changes []PlanChange
+	matched := map[string]bool{}
+	seen := map[string]bool{}
+
+	for _, doc := range wanted {
+		uuid, _ := doc["uuid"].(string)
+		name, _ := doc["name"].(string)
+		if name == "" {
+			return nil, nil, fmt.Errorf("goplatform: plan: %s without name", kind)
+		}
+		if seen[name] {
+			return nil, nil, fmt.Errorf("goplatform: plan: duplicate %s %q", kind, name)
+		}
+		seen[name] = true
+
+		desired := stripFields(doc, serverFields[kind])
+		_ = resolveReferences(kind, desired, names, nil)
+
+		current, ok := byUuid[uuid]
+		if uuid == "" || !ok {
+			current, ok = byName[name]
+		}
+
+		if !ok {
+			changes = append(changes, PlanChange{Action: PLAN_CREATE, Kind: kind, Name: name, Document: desired})
+			continue
+		}
+
+		currentUuid, _ := current["uuid"].(string)
+		matched[currentUuid] = true
+
+		base := stripFields(current, serverFields[kind])
+		diffs := diffDocuments("", base, desired, true)
+		if len(diffs) == 0 {
+			continue
+		}
+
+		merged := mergeDocuments(base, desired)
+		merged["uuid"] = currentUuid
+
+		changes = append(changes, PlanChange{Action: PLAN_UPDATE, Kind: kind, Name: name, Uuid: currentUuid, Diffs: diffs, Document: merged})
+	}
+
+	var deletes []PlanChange
+	for _, doc := range actual {
+		uuid, _ := doc["uuid"].(string)
+		name, _ := doc["name"].(string)
+		if !matched[uuid] {
+			deletes = append(deletes, PlanChange{Action: PLAN_DELETE, Kind: kind, Name: name, Uuid: uuid})
+		}
+	}
+
+	return changes, deletes, nil
+}
+
+// Apply applies the changes of plan in order. A failing change does not stop
+// the others, except those referencing a resource whose creation failed.
+func (p Project) Apply(ctx context.Context, plan Plan) (ApplyResult, error) {
+	var result ApplyResult
+
+	names := map[ResourceKind]map[string]string{}
+	failed := map[ResourceKind]map[string]bool{}
+	for _, kind := range resourceOrder {
+		names[kind] = map[string]string{}
+		failed[kind] = map[string]bool{}
+	}
+
+	var errs []error
+	for _, change := range plan.Changes {
+		err := p.applyChange(ctx, change, names, failed)
+		if err != nil {
+			failed[change.Kind][change.Name] = true
+			result.Failed = append(result.Failed, ApplyFailure{Change: change, Err: err})
+			errs = append(errs, fmt.Errorf("%s %s %q: %w", change.Action, change.Kind, change.Name, err))
+			continue
+		}
+		result.Applied = append(result.Applied, change)
+	}
+
+	if len(errs) > 0 {
+		return result, fmt.Errorf("goplatform: apply: %d of %d changes failed: %w", len(errs), len(plan.Changes), errors.Join(errs...))
+	}
+
+	return result, nil
+}
+
+func (p Project) applyChange(ctx context.Context, change PlanChange, names map[ResourceKind]map[string]string, failed map[ResourceKind]map[string]bool) error {
+	path := resourcePaths[change.Kind]
+
+	switch change.Action {
+	case PLAN_DELETE:
+		_, err := p.platformRef.fetch(ctx, httpDelete, nil, "projects", p.Uuid, path, change.Uuid)
+		return err
+	case PLAN_CREATE, PLAN_UPDATE:
+		doc := cloneDocument(change.Document)
+		if err := resolveReferences(change.Kind, doc, names, failed); err != nil {
+			return err
+		}
+		doc["projectId"] = p.Uuid
+
+		if change.Action == PLAN_UPDATE {
+			_, err := sendResource[map[string]any](ctx, p.platformRef, httpPut, doc, "projects", p.Uuid, path, change.Uuid)
+			return err
+		}
+
+		created, err := sendResource[map[string]any](ctx, p.platformRef, httpPost, doc, "projects", p.Uuid, path)
+		if err != nil {
+			return err
+		}
+		if uuid, ok := created["uuid"].(string); ok {
+			names[change.Kind][change.Name] = uuid
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown action %q", change.Action)
+	}
+}
+
+// resolveReferences replaces the names of referenced resources with their
+// UUID, failing when the referenced resource could not be created.
+func resolveReferences(kind ResourceKind, doc map[string]any, names map[ResourceKind]map[string]string, failed map[ResourceKind]map[string]bool) error {
+	var errs []error
+
+	resolve := func(target ResourceKind, value any) any {
+		s, ok := value.(string)
+		if !ok || s == "" {
+			return value
+		}
+		if failed[target][s] {
+			errs = append(errs, fmt.Errorf("depends on %s %q which failed", target, s))
+			return value
+		}
+		if uuid, ok := names[target][s]; ok && uuid != "" {
+			return uuid
+		}
+		return value
+	}
+
+	switch kind {
+	case RESOURCE_DEVICE:
+		if v, ok := doc["deviceTypeId"]; ok {
+			doc["deviceTypeId"] = resolve(RESOURCE_DEVICE_TYPE, v)
+		}
+		if v, ok := doc["nodeId"]; ok {
+			doc["nodeId"] = resolve(RESOURCE_NODE, v)
+		}
+	case RESOURCE_RULE:
+		for _, key := range []string{"triggers", "actions", "elseActions"} {
+			items, _ := doc[key].([]any)
+			for _, item := range items {
+				m, ok := item.(map[string]any)
+				if !ok {
+					continue
+				}
+				if v, ok := m["deviceId"]; ok {
+					m["deviceId"] = resolve(RESOURCE_DEVICE, v)
+				}
+				if command, ok := m["command"].(map[string]any); ok {
+					if v, ok := command["deviceId"]; ok {
+						command["deviceId"] = resolve(RESOURCE_DEVICE, v)
+					}
+				}
+			}
+		}
+		if condition, ok := doc["condition"].(map[string]any); ok {
+			if devices, ok := condition["devices"].(map[string]any); ok {
+				for alias, v := range devices {
+					devices[alias] = resolve(RESOURCE_DEVICE, v)
+				}
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// diffDocuments returns the fields changed from current to desired,
+// descending into nested objects. When partial, only the fields present in
+// desired are compared; otherwise added and removed fields are reported too.
+func diffDocuments(prefix string, current, desired map[string]any, partial bool) []PlanDiff {
+	keys := slices.Collect(maps.Keys(desired))
+	if !partial {
+		for key := range current {
+			if _, ok := desired[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+	}
+	slices.Sort(keys)
+
+	var diffs []PlanDiff
+	for _, key := range keys {
+		want := desired[key]
+
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+
+		have := current[key]
+		wantMap, wok := want.(map[string]any)
+		haveMap, hok := have.(map[string]any)
+		if wok && hok {
+			diffs = append(diffs, diffDocuments(path, haveMap, wantMap, partial)...)
+			continue
+		}
+
+		if !reflect.DeepEqual(want, have) {
+			diffs = append(diffs, PlanDiff{Path: path, Old: have, New: want})
+		}
+	}
+
+	return diffs
+}
+
+// Empty reports whether the plan has no changes.
+func (p Plan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// String renders the plan as a human-readable diff.
+func (p Plan) String() string {
+	var b strings.Builder
+
+	counts := map[PlanAction]int{}
+	for _, change := range p.Changes {
+		counts[change.Action]++
+
+		switch change.Action {
+		case PLAN_CREATE:
+			fmt.Fprintf(&b, "+ %s %q\n", change.Kind, change.Name)
+		case PLAN_UPDATE:
+			fmt.Fprintf(&b, "~ %s %q (%s)\n", change.Kind, change.Name, change.Uuid)
+			for _, diff := range change.Diffs {
+				fmt.Fprintf(&b, "    %s: %s => %s\n", diff.Path, formatPlanValue(diff.Old), formatPlanValue(diff.New))
+			}
+		case PLAN_DELETE:
+			fmt.Fprintf(&b, "- %s %q (%s)\n", change.Kind, change.Name, change.Uuid)
+		}
+	}
+
+	fmt.Fprintf(&b, "Plan: %d to create, %d to update, %d to delete.\n", counts[PLAN_CREATE], counts[PLAN_UPDATE], counts[PLAN_DELETE])
+
+	return b.String()
+}
+
+func formatPlanValue(value any) string {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(b)
+}
+
+// toDocument converts resource to its JSON form decoded as T.
+func toDocument[T any](resource any) (T, error) {
+	var doc T
+
+	b, err := json.Marshal(resource)
+	if err != nil {
+		return doc, err
+	}
+	err = json.Unmarshal(b, &doc)
+
+	return doc, err
+}
+
+// toDocuments converts a list of resources to documents sorted by name.
+func toDocuments(resources any) ([]map[string]any, error) {
+	docs, err := toDocument[[]map[string]any](resources)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(docs, func(i, j int) bool {
+		a, _ := docs[i]["name"].(string)
+		b, _ := docs[j]["name"].(string)
+		return a < b
+	})
+
+	return docs, nil
+}
+
+// maskDocument keeps the fields of doc present in mask, descending into
+// nested objects. Fields of mask omitted from doc are taken from mask.
+func maskDocument(doc, mask map[string]any) map[string]any {
+	res := map[string]any{}
+	for key, m := range mask {
+		value, ok := doc[key]
+		if !ok {
+			res[key] = cloneValue(m)
+			continue
+		}
+
+		valueMap, vok := value.(map[string]any)
+		maskMap, mok := m.(map[string]any)
+		if vok && mok {
+			value = maskDocument(valueMap, maskMap)
+		}
+		res[key] = value
+	}
+	return res
+}
+
+// pruneDocument removes the null, empty, false and zero fields of doc,
+// descending into nested objects.
+func pruneDocument(doc map[string]any) map[string]any {
+	res := map[string]any{}
+	for key, value := range doc {
+		switch v := value.(type) {
+		case nil:
+			continue
+		case string, bool, float64:
+			if reflect.ValueOf(v).IsZero() {
+				continue
+			}
+		case map[string]any:
+			value = pruneDocument(v)
+		}
+		res[key] = value
+	}
+	return res
+}
+
+// mergeDocuments returns a copy of base with the fields of patch set,
+// descending into nested objects.
+func mergeDocuments(base, patch map[string]any) map[string]any {
+	res := cloneDocument(base)
+	for key, value := range patch {
+		valueMap, vok := value.(map[string]any)
+		baseMap, bok := res[key].(map[string]any)
+		if vok && bok {
+			res[key] = mergeDocuments(baseMap, valueMap)
+			continue
+		}
+		res[key] = cloneValue(value)
+	}
+	return res
+}
+
+func stripFields(doc map[string]any, fields []string) map[string]any {
+	res := cloneDocument(doc)
+	for _, field := range fields {
+		delete(res, field)
+	}
+	return res
+}
+
+// cloneDocument deep copies a JSON document.
+func cloneDocument(doc map[string]any) map[string]any {
+	res := make(map[string]any, len(doc))
+	for k, v := range doc {
+		res[k] = cloneValue(v)
+	}
+	return res
+}
+
+func cloneValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		return cloneDocument(v)
+	case []any:
+		res := make([]any, len(v))
+		for i, item := range v {
+			res[i] = cloneValue(item)
+		}
+		return res
+	default:
+		return v
+	}
+}
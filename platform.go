@@ -1,9 +1,11 @@
 package goplatform
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -20,15 +22,42 @@ const (
 	httpGet    httpMethod = "GET"
 	httpPost   httpMethod = "POST"
 	httpPut    httpMethod = "PUT"
+	httpPatch  httpMethod = "PATCH"
 	httpDelete httpMethod = "DELETE"
 )
 
-type Platform struct {
+// Client is the connection to Apio Platform shared by every resource it
+// returns. A Client is safe for concurrent use by multiple goroutines and
+// should be reused rather than created per request.
+type Client struct {
 	uri    string
 	apiKey string
 	client *http.Client
 }
 
+// Platform is the value form of Client returned by New. Copies of a Platform
+// share the same Client. The zero Platform has no Client: its methods fail
+// until it is created with New.
+type Platform struct {
+	*Client
+}
+
+// APIError is returned when the platform replies with an error status.
+type APIError struct {
+	Method     string
+	Url        string
+	StatusCode int
+	Name       string
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.Name == "" && e.Message == "" {
+		return fmt.Sprintf("goplatform: %s %s: status %d", e.Method, e.Url, e.StatusCode)
+	}
+	return fmt.Sprintf("goplatform: %s %s: %s: %s", e.Method, e.Url, e.Name, e.Message)
+}
+
 type Config struct {
 	Uri        string
 	ApiKey     string
@@ -50,6 +79,10 @@ func isTest() bool {
 }
 
 func New(config Config) Platform {
+	return Platform{NewClient(config)}
+}
+
+func NewClient(config Config) *Client {
 	client := &http.Client{}
 
 	if config.SkipVerify {
@@ -62,14 +95,18 @@ func New(config Config) Platform {
 		gock.InterceptClient(client) // For test
 	}
 
-	return Platform{
+	return &Client{
 		uri:    config.Uri,
 		apiKey: config.ApiKey,
 		client: client,
 	}
 }
 
-func (p Platform) fetch(ctx context.Context, method httpMethod, body io.Reader, path ...string) ([]byte, error) {
+func (p *Client) newRequest(ctx context.Context, method httpMethod, body io.Reader, path ...string) (*http.Request, error) {
+	if p == nil || p.client == nil {
+		return nil, errors.New("goplatform: client not initialized, use New or NewClient")
+	}
+
 	baseUri, err := url.Parse(p.uri)
 	if err != nil {
 		return nil, err
@@ -91,6 +128,28 @@ func (p Platform) fetch(ctx context.Context, method httpMethod, body io.Reader,
 		req.Header.Set("Authorization", fmt.Sprintf("apiKey %s", p.apiKey))
 	}
 
+	return req, nil
+}
+
+func (p *Client) fetch(ctx context.Context, method httpMethod, body io.Reader, path ...string) ([]byte, error) {
+	return p.fetchQuery(ctx, method, body, nil, path...)
+}
+
+func (p *Client) fetchQuery(ctx context.Context, method httpMethod, body io.Reader, query url.Values, path ...string) ([]byte, error) {
+	req, err := p.newRequest(ctx, method, body, path...)
+	if err != nil {
+		return nil, err
+	}
+	if len(query) > 0 {
+		req.URL.RawQuery = query.Encode()
+	}
+
+	return p.do(req)
+}
+
+// do sends req and returns the response body, or an *APIError for error
+// statuses.
+func (p *Client) do(req *http.Request) ([]byte, error) {
 	resp, err := p.client.Do(req)
 	if err != nil {
 		return nil, err
@@ -98,24 +157,38 @@ func (p Platform) fetch(ctx context.Context, method httpMethod, body io.Reader,
 	defer resp.Body.Close() //nolint:errcheck
 
 	if resp.StatusCode >= 400 {
-		b, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return nil, fmt.Errorf("goplatform: ERR0: some error on %s %s", resp.Request.Method, resp.Request.URL)
-		}
+		return nil, newAPIError(resp)
+	}
 
-		var payload responseError
-		if err := json.Unmarshal(b, &payload); err != nil {
-			return nil, fmt.Errorf("goplatform: ERR1: some error on %s %s", resp.Request.Method, resp.Request.URL)
-		}
+	b, err := io.ReadAll(resp.Body)
+	return b, err
+}
 
-		return nil, fmt.Errorf("goplatform: %s %s: %s: %s", resp.Request.Method, resp.Request.URL, payload.Error.Name, payload.Error.Message)
+// newAPIError reads the error payload of resp, if any.
+func newAPIError(resp *http.Response) *APIError {
+	apiErr := &APIError{
+		Method:     resp.Request.Method,
+		Url:        resp.Request.URL.String(),
+		StatusCode: resp.StatusCode,
 	}
 
 	b, err := io.ReadAll(resp.Body)
-	return b, err
+	if err != nil {
+		return apiErr
+	}
+
+	var payload responseError
+	if err := json.Unmarshal(b, &payload); err != nil {
+		return apiErr
+	}
+
+	apiErr.Name = payload.Error.Name
+	apiErr.Message = payload.Error.Message
+
+	return apiErr
 }
 
-func (p Platform) GetProjects(ctx context.Context) ([]Project, error) {
+func (p *Client) GetProjects(ctx context.Context) ([]Project, error) {
 	b, err := p.fetch(ctx, httpGet, nil, "projects")
 	if err != nil {
 		return nil, err
@@ -127,13 +200,13 @@ func (p Platform) GetProjects(ctx context.Context) ([]Project, error) {
 	}
 
 	for i := 0; i < len(projects.Data); i++ {
-		projects.Data[i].platformRef = &p
+		projects.Data[i].platformRef = p
 	}
 
 	return projects.Data, nil
 }
 
-func (p Platform) GetProject(ctx context.Context, uuid string) (Project, error) {
+func (p *Client) GetProject(ctx context.Context, uuid string) (Project, error) {
 	b, err := p.fetch(ctx, httpGet, nil, "projects", uuid, "/")
 	if err != nil {
 		var zero Project
@@ -146,7 +219,36 @@ func (p Platform) GetProject(ctx context.Context, uuid string) (Project, error)
 		return zero, err
 	}
 
-	project.Data.platformRef = &p
+	project.Data.platformRef = p
 
 	return project.Data, nil
 }
+
+// Project returns a handle on the project uuid without fetching it. Every
+// project-scoped method works on the handle; call Load for the full project.
+func (p *Client) Project(uuid string) Project {
+	return Project{Uuid: uuid, platformRef: p}
+}
+
+// sendResource sends body as JSON and decodes the resource returned in the
+// response data.
+func sendResource[T any](ctx context.Context, p *Client, method httpMethod, body any, path ...string) (T, error) {
+	var zero T
+
+	b, err := json.Marshal(body)
+	if err != nil {
+		return zero, err
+	}
+
+	b, err = p.fetch(ctx, method, bytes.NewReader(b), path...)
+	if err != nil {
+		return zero, err
+	}
+
+	var res response[T]
+	if err := json.Unmarshal(b, &res); err != nil {
+		return zero, err
+	}
+
+	return res.Data, nil
+}
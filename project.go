@@ -15,7 +15,13 @@ type Project struct {
 	Features    []any          `json:"features"`
 	CreatedAt   time.Time      `json:"createdAt,omitempty"`
 	UpdatedAt   time.Time      `json:"updatedAt,omitempty"`
-	platformRef *Platform      `json:"-"`
+	platformRef *Client        `json:"-"`
+}
+
+// Load fetches the full project, typically of a handle returned by
+// Client.Project.
+func (p Project) Load(ctx context.Context) (Project, error) {
+	return p.platformRef.GetProject(ctx, p.Uuid)
 }
 
 func (p Project) GetNodes(ctx context.Context) ([]Node, error) {
@@ -104,6 +110,7 @@ func (p Project) GetDeviceTypes(ctx context.Context) ([]DeviceType, error) {
 
 	for i := 0; i < len(devices.Data); i++ {
 		devices.Data[i].platformRef = p.platformRef
+		devices.Data[i].projectRef = p.Uuid
 	}
 
 	return devices.Data, nil
@@ -122,6 +129,7 @@ func (p Project) GetDeviceType(ctx context.Context, uuid string) (DeviceType, er
 	}
 
 	device.Data.platformRef = p.platformRef
+	device.Data.projectRef = p.Uuid
 
 	return device.Data, nil
 }
@@ -184,3 +192,18 @@ func (p Project) SendCommand(ctx context.Context, command Command) error {
 	_, err = p.platformRef.fetch(ctx, httpPost, bytes.NewReader(b), "projects", p.Uuid, "commands")
 	return err
 }
+
+func (p Project) GetCommand(ctx context.Context, uuid string) (Command, error) {
+	b, err := p.platformRef.fetch(ctx, httpGet, nil, "projects", p.Uuid, "commands", uuid)
+	if err != nil {
+		var zero Command
+		return zero, err
+	}
+
+	var command response[Command]
+	if err := json.Unmarshal(b, &command); err != nil {
+		return command.Data, err
+	}
+
+	return command.Data, nil
+}
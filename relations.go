@@ -0,0 +1,165 @@
+package goplatform
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// projectOf returns a handle on the project uuid of a resource fetched
+// through ref.
+func projectOf(ref *Client, kind, uuid, projectId string) (Project, error) {
+	if ref == nil {
+		return Project{}, fmt.Errorf("goplatform: %s %s: not fetched from a platform", kind, uuid)
+	}
+	if projectId == "" {
+		return Project{}, fmt.Errorf("goplatform: %s %s: missing project", kind, uuid)
+	}
+	return ref.Project(projectId), nil
+}
+
+// Node returns the node the device is attached to.
+func (d Device) Node(ctx context.Context) (Node, error) {
+	project, err := projectOf(d.platformRef, "device", d.Uuid, d.ProjectID)
+	if err != nil {
+		return Node{}, err
+	}
+	if d.NodeID == "" {
+		return Node{}, fmt.Errorf("goplatform: device %s: not attached to a node", d.Uuid)
+	}
+
+	return project.GetNode(ctx, d.NodeID)
+}
+
+// Type returns the device type of the device, without a request when the
+// device embeds it.
+func (d Device) Type(ctx context.Context) (DeviceType, error) {
+	if d.DeviceType != nil && (d.DeviceTypeID == "" || d.DeviceType.Uuid == "" || d.DeviceType.Uuid == d.DeviceTypeID) {
+		res := *d.DeviceType
+		if res.platformRef == nil {
+			res.platformRef = d.platformRef
+		}
+		res.projectRef = d.ProjectID
+		return res, nil
+	}
+
+	project, err := projectOf(d.platformRef, "device", d.Uuid, d.ProjectID)
+	if err != nil {
+		return DeviceType{}, err
+	}
+	if d.DeviceTypeID == "" {
+		return DeviceType{}, fmt.Errorf("goplatform: device %s: missing device type", d.Uuid)
+	}
+
+	return project.GetDeviceType(ctx, d.DeviceTypeID)
+}
+
+// Devices returns the devices attached to the node.
+func (n Node) Devices(ctx context.Context) ([]Device, error) {
+	project, err := projectOf(n.platformRef, "node", n.Uuid, n.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	devices, err := project.GetDevices(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []Device
+	for _, d := range devices {
+		if d.NodeID == n.Uuid {
+			res = append(res, d)
+		}
+	}
+
+	return res, nil
+}
+
+// Devices returns the devices using the device type in the project it was
+// fetched through, which for shared device types may differ from ProjectID.
+func (dt DeviceType) Devices(ctx context.Context) ([]Device, error) {
+	projectId := dt.projectRef
+	if projectId == "" {
+		projectId = dt.ProjectID
+	}
+
+	project, err := projectOf(dt.platformRef, "device type", dt.Uuid, projectId)
+	if err != nil {
+		return nil, err
+	}
+
+	devices, err := project.GetDevices(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []Device
+	for _, d := range devices {
+		if d.DeviceTypeID == dt.Uuid {
+			res = append(res, d)
+		}
+	}
+
+	return res, nil
+}
+
+// ReferencedDeviceIds returns the devices referenced by the rule triggers,
+// condition and actions, in order of first appearance.
+func (r Rule) ReferencedDeviceIds() ([]string, error) {
+	var ids []string
+	seen := map[string]bool{}
+	add := func(id string) {
+		if id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	for _, trigger := range r.Triggers {
+		add(deref(trigger.DeviceId))
+	}
+
+	if r.Condition != nil && strings.TrimSpace(r.Condition.Condition) != "" {
+		compiled, err := r.Condition.Compile()
+		if err != nil {
+			return nil, err
+		}
+		for _, ref := range compiled.References() {
+			add(ref.DeviceId)
+		}
+	}
+
+	for _, action := range append(append([]RuleAction{}, r.Actions...), r.ElseActions...) {
+		add(deref(action.DeviceId))
+		if id, ok := action.Command["deviceId"].(string); ok {
+			add(id)
+		}
+	}
+
+	return ids, nil
+}
+
+// ReferencedDevices fetches the devices returned by ReferencedDeviceIds.
+func (r Rule) ReferencedDevices(ctx context.Context) ([]Device, error) {
+	project, err := projectOf(r.platformRef, "rule", r.Uuid, r.ProjectId)
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := r.ReferencedDeviceIds()
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]Device, 0, len(ids))
+	for _, id := range ids {
+		device, err := project.GetDevice(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("goplatform: rule %s: %w", r.Uuid, err)
+		}
+		res = append(res, device)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,263 @@
+package goplatform
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"time"
+)
+
+// RuleActionVariant is the typed form of a RuleAction. It is implemented by
+// WasmRuleAction, LogRuleAction, WebhookRuleAction, CommandRuleAction and
+// DelayRuleAction.
+type RuleActionVariant interface {
+	Action() RuleAction
+}
+
+type WasmRuleAction struct {
+	Label    string
+	Language string
+	Source   string
+	Binary   string
+	Hash     string
+	// verified is the module decoded from Binary by Variant.
+	verified *wasmModule
+}
+
+type LogRuleAction struct {
+	Value string
+}
+
+type WebhookRuleAction struct {
+	Uri     string
+	Payload string
+}
+
+type CommandRuleAction struct {
+	DeviceId string
+	Command  map[string]any
+}
+
+type DelayRuleAction struct {
+	Duration time.Duration
+}
+
+func WasmAction(label, language, source string) RuleAction {
+	return WasmRuleAction{Label: label, Language: language, Source: source}.Action()
+}
+
+func LogAction(value string) RuleAction {
+	return LogRuleAction{Value: value}.Action()
+}
+
+func WebhookAction(uri, payload string) RuleAction {
+	return WebhookRuleAction{Uri: uri, Payload: payload}.Action()
+}
+
+// CommandAction returns an action sending command. The action targets the
+// command DeviceId, if any.
+func CommandAction(command CommandRequest) RuleAction {
+	payload := map[string]any{
+		"name":       command.Name,
+		"parameters": command.Parameters,
+	}
+	if command.ProjectId != "" {
+		payload["projectId"] = command.ProjectId
+	}
+	if command.NodeId != nil {
+		payload["nodeId"] = *command.NodeId
+	}
+	if command.DeviceId != nil {
+		payload["deviceId"] = *command.DeviceId
+	}
+	if command.Metadata != nil {
+		payload["metadata"] = command.Metadata
+	}
+	if command.DownlinkRetry != nil {
+		payload["downlinkRetry"] = command.DownlinkRetry
+	}
+	if command.ExecutionRetry != nil {
+		payload["executionRetry"] = command.ExecutionRetry
+	}
+
+	return CommandRuleAction{DeviceId: deref(command.DeviceId), Command: payload}.Action()
+}
+
+func DelayAction(d time.Duration) RuleAction {
+	return DelayRuleAction{Duration: d}.Action()
+}
+
+func (a WasmRuleAction) Action() RuleAction {
+	return RuleAction{
+		Type:     RULE_ACTION_WASM,
+		Label:    optionalString(a.Label),
+		Language: optionalString(a.Language),
+		Source:   optionalString(a.Source),
+		Binary:   optionalString(a.Binary),
+		Hash:     optionalString(a.Hash),
+	}
+}
+
+func (a LogRuleAction) Action() RuleAction {
+	return RuleAction{Type: RULE_ACTION_LOG, Value: &a.Value}
+}
+
+func (a WebhookRuleAction) Action() RuleAction {
+	return RuleAction{Type: RULE_ACTION_WEBHOOK, Uri: &a.Uri, Payload: optionalString(a.Payload)}
+}
+
+func (a CommandRuleAction) Action() RuleAction {
+	return RuleAction{Type: RULE_ACTION_COMMAND, Command: a.Command, DeviceId: optionalString(a.DeviceId)}
+}
+
+func (a DelayRuleAction) Action() RuleAction {
+	ms := a.Duration.Milliseconds()
+	return RuleAction{Type: RULE_ACTION_DELAY, Time: &ms}
+}
+
+// Variant returns the typed form of the action, to be inspected with a type
+// switch.
+func (a RuleAction) Variant() (RuleActionVariant, error) {
+	module, err := a.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	switch a.Type {
+	case RULE_ACTION_WASM:
+		action := WasmRuleAction{
+			Label:    deref(a.Label),
+			Language: deref(a.Language),
+			Source:   deref(a.Source),
+			Binary:   deref(a.Binary),
+			Hash:     deref(a.Hash),
+		}
+		if module != nil {
+			action.verified = &wasmModule{binary: action.Binary, hash: action.Hash, module: module}
+		}
+		return action, nil
+	case RULE_ACTION_LOG:
+		return LogRuleAction{Value: *a.Value}, nil
+	case RULE_ACTION_WEBHOOK:
+		return WebhookRuleAction{Uri: *a.Uri, Payload: deref(a.Payload)}, nil
+	case RULE_ACTION_COMMAND:
+		return CommandRuleAction{DeviceId: deref(a.DeviceId), Command: a.Command}, nil
+	default:
+		return DelayRuleAction{Duration: time.Duration(*a.Time) * time.Millisecond}, nil
+	}
+}
+
+// Validate reports the fields that are missing or irrelevant for the action
+// type.
+func (a RuleAction) Validate() error {
+	_, err := a.validate()
+	return err
+}
+
+// validate checks the action, returning the module decoded from the binary
+// of wasm actions.
+func (a RuleAction) validate() ([]byte, error) {
+	fields := map[string]bool{
+		"label":    a.Label != nil,
+		"language": a.Language != nil,
+		"source":   a.Source != nil,
+		"binary":   a.Binary != nil,
+		"hash":     a.Hash != nil,
+		"value":    a.Value != nil,
+		"uri":      a.Uri != nil,
+		"payload":  a.Payload != nil,
+		"command":  a.Command != nil,
+		"deviceId": a.DeviceId != nil,
+		"time":     a.Time != nil,
+	}
+
+	var allowed []string
+	var errs []error
+	var module []byte
+
+	switch a.Type {
+	case RULE_ACTION_WASM:
+		allowed = []string{"label", "language", "source", "binary", "hash"}
+		if deref(a.Language) == "" {
+			errs = append(errs, errors.New("missing language"))
+		}
+		if deref(a.Source) == "" && deref(a.Binary) == "" {
+			errs = append(errs, errors.New("missing source or binary"))
+		}
+		if deref(a.Binary) != "" {
+			var err error
+			if module, err = decodeWasmBinary(*a.Binary, deref(a.Hash)); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	case RULE_ACTION_LOG:
+		allowed = []string{"value"}
+		if a.Value == nil {
+			errs = append(errs, errors.New("missing value"))
+		}
+	case RULE_ACTION_WEBHOOK:
+		allowed = []string{"uri", "payload"}
+		if deref(a.Uri) == "" {
+			errs = append(errs, errors.New("missing uri"))
+		} else if u, err := url.Parse(*a.Uri); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("invalid uri %q", *a.Uri))
+		}
+	case RULE_ACTION_COMMAND:
+		allowed = []string{"command", "deviceId"}
+		if len(a.Command) == 0 {
+			errs = append(errs, errors.New("missing command"))
+		} else if name, _ := a.Command["name"].(string); name == "" {
+			errs = append(errs, errors.New("missing command name"))
+		}
+	case RULE_ACTION_DELAY:
+		allowed = []string{"time"}
+		if a.Time == nil {
+			errs = append(errs, errors.New("missing time"))
+		} else if *a.Time < 0 {
+			errs = append(errs, fmt.Errorf("negative time %d", *a.Time))
+		}
+	default:
+		return nil, fmt.Errorf("goplatform: rule action: unknown type %q", a.Type)
+	}
+
+	errs = append(errs, irrelevantFields(fields, allowed)...)
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("goplatform: rule action %s: %w", a.Type, errors.Join(errs...))
+	}
+
+	return module, nil
+}
+
+// irrelevantFields returns an error for every set field not in allowed, in a
+// stable order.
+func irrelevantFields(fields map[string]bool, allowed []string) []error {
+	permitted := map[string]bool{}
+	for _, name := range allowed {
+		permitted[name] = true
+	}
+
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(fields)) {
+		if fields[name] && !permitted[name] {
+			errs = append(errs, fmt.Errorf("irrelevant field %s", name))
+		}
+	}
+	return errs
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func deref[T any](p *T) T {
+	var zero T
+	if p == nil {
+		return zero
+	}
+	return *p
+}
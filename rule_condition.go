@@ -0,0 +1,625 @@
+package goplatform
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ConditionReference is a device property referenced by a rule condition.
+type ConditionReference struct {
+	Alias    string `json:"alias"`
+	DeviceId string `json:"deviceId"`
+	Property string `json:"property"`
+}
+
+// CompiledCondition is a parsed RuleCondition ready to be evaluated.
+type CompiledCondition struct {
+	source  string
+	devices map[string]string
+	root    conditionNode
+	refs    []ConditionReference
+}
+
+// Compile parses the condition expression and checks that every alias it
+// references is declared in Devices.
+//
+// The expression language is the JavaScript subset used by Apio Platform:
+// number, string, boolean and null literals, alias.property and
+// alias["property"] references, the unary operators ! and -, the arithmetic
+// operators * / % + -, the comparison operators < <= > >= == != === !==, the
+// logical operators && || and parentheses.
+func (c RuleCondition) Compile() (CompiledCondition, error) {
+	var zero CompiledCondition
+
+	p := conditionParser{source: c.Condition}
+	if err := p.tokenize(); err != nil {
+		return zero, err
+	}
+
+	root, err := p.parseExpression(0)
+	if err != nil {
+		return zero, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return zero, p.errorf(tok, "unexpected %q", tok.text)
+	}
+
+	compiled := CompiledCondition{
+		source:  c.Condition,
+		devices: c.Devices,
+		root:    root,
+	}
+
+	seen := map[ConditionReference]bool{}
+	var walkErr error
+	walkCondition(root, func(n conditionNode) {
+		ref, ok := n.(conditionRef)
+		if !ok || walkErr != nil {
+			return
+		}
+		deviceId, ok := c.Devices[ref.alias]
+		if !ok {
+			walkErr = fmt.Errorf("goplatform: condition: unknown device alias %q", ref.alias)
+			return
+		}
+		r := ConditionReference{Alias: ref.alias, DeviceId: deviceId, Property: ref.property}
+		if !seen[r] {
+			seen[r] = true
+			compiled.refs = append(compiled.refs, r)
+		}
+	})
+	if walkErr != nil {
+		return zero, walkErr
+	}
+
+	return compiled, nil
+}
+
+// Evaluate compiles the condition and evaluates it against states, the
+// device states keyed by device UUID.
+func (c RuleCondition) Evaluate(states map[string]map[string]any) (bool, error) {
+	compiled, err := c.Compile()
+	if err != nil {
+		return false, err
+	}
+	return compiled.Evaluate(states)
+}
+
+// String returns the source expression.
+func (c CompiledCondition) String() string {
+	return c.source
+}
+
+// References returns the device properties referenced by the condition, in
+// order of appearance.
+func (c CompiledCondition) References() []ConditionReference {
+	res := make([]ConditionReference, len(c.refs))
+	copy(res, c.refs)
+	return res
+}
+
+// Evaluate evaluates the condition against states, the device states keyed by
+// device UUID. Every referenced device must have a state; missing properties
+// evaluate to null.
+func (c CompiledCondition) Evaluate(states map[string]map[string]any) (bool, error) {
+	if c.root == nil {
+		return false, fmt.Errorf("goplatform: condition: not compiled")
+	}
+
+	ids := make([]string, 0, len(c.refs))
+	for _, ref := range c.refs {
+		ids = append(ids, ref.DeviceId)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if _, ok := states[id]; !ok {
+			return false, fmt.Errorf("goplatform: condition: missing state for device %s", id)
+		}
+	}
+
+	env := conditionEnv{devices: c.devices, states: states}
+	value, err := c.root.eval(env)
+	if err != nil {
+		return false, err
+	}
+
+	return truthy(value), nil
+}
+
+type conditionEnv struct {
+	devices map[string]string
+	states  map[string]map[string]any
+}
+
+type conditionNode interface {
+	eval(env conditionEnv) (any, error)
+}
+
+type conditionLiteral struct {
+	value any
+}
+
+type conditionRef struct {
+	alias    string
+	property string
+}
+
+type conditionUnary struct {
+	op      string
+	operand conditionNode
+}
+
+type conditionBinary struct {
+	op          string
+	left, right conditionNode
+}
+
+func (n conditionLiteral) eval(conditionEnv) (any, error) {
+	return n.value, nil
+}
+
+func (n conditionRef) eval(env conditionEnv) (any, error) {
+	state := env.states[env.devices[n.alias]]
+	return normalizeConditionValue(state[n.property]), nil
+}
+
+func (n conditionUnary) eval(env conditionEnv) (any, error) {
+	value, err := n.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "!":
+		return !truthy(value), nil
+	case "-":
+		f, ok := toNumber(value)
+		if !ok {
+			return nil, fmt.Errorf("goplatform: condition: cannot negate %v", value)
+		}
+		return -f, nil
+	default:
+		return nil, fmt.Errorf("goplatform: condition: unknown operator %q", n.op)
+	}
+}
+
+func (n conditionBinary) eval(env conditionEnv) (any, error) {
+	left, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "&&":
+		if !truthy(left) {
+			return left, nil
+		}
+		return n.right.eval(env)
+	case "||":
+		if truthy(left) {
+			return left, nil
+		}
+		return n.right.eval(env)
+	}
+
+	right, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return looseEqual(left, right), nil
+	case "!=":
+		return !looseEqual(left, right), nil
+	case "===":
+		return strictEqual(left, right), nil
+	case "!==":
+		return !strictEqual(left, right), nil
+	case "+":
+		ls, lok := left.(string)
+		rs, rok := right.(string)
+		if lok || rok {
+			if !lok {
+				ls = conditionString(left)
+			}
+			if !rok {
+				rs = conditionString(right)
+			}
+			return ls + rs, nil
+		}
+	case "<", "<=", ">", ">=":
+		ls, lok := left.(string)
+		rs, rok := right.(string)
+		if lok && rok {
+			return compareOrdered(n.op, strings.Compare(ls, rs)), nil
+		}
+	}
+
+	l, lok := toNumber(left)
+	r, rok := toNumber(right)
+	if !lok || !rok {
+		return nil, fmt.Errorf("goplatform: condition: invalid operands %v %s %v", left, n.op, right)
+	}
+
+	switch n.op {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "/":
+		return l / r, nil
+	case "%":
+		return math.Mod(l, r), nil
+	case "<", "<=", ">", ">=":
+		if math.IsNaN(l) || math.IsNaN(r) {
+			return false, nil
+		}
+		cmp := 0
+		if l < r {
+			cmp = -1
+		} else if l > r {
+			cmp = 1
+		}
+		return compareOrdered(n.op, cmp), nil
+	default:
+		return nil, fmt.Errorf("goplatform: condition: unknown operator %q", n.op)
+	}
+}
+
+func compareOrdered(op string, cmp int) bool {
+	switch op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default:
+		return cmp >= 0
+	}
+}
+
+func walkCondition(n conditionNode, fn func(conditionNode)) {
+	fn(n)
+	switch v := n.(type) {
+	case conditionUnary:
+		walkCondition(v.operand, fn)
+	case conditionBinary:
+		walkCondition(v.left, fn)
+		walkCondition(v.right, fn)
+	}
+}
+
+// normalizeConditionValue maps Go values onto the float64, string, bool and
+// nil values the evaluator works with.
+func normalizeConditionValue(value any) any {
+	switch v := value.(type) {
+	case nil, string, bool, float64:
+		return v
+	default:
+		if f, ok := toFloat64(v); ok {
+			return f
+		}
+		return v
+	}
+}
+
+func truthy(value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case float64:
+		return v != 0 && !math.IsNaN(v)
+	case string:
+		return v != ""
+	default:
+		return true
+	}
+}
+
+func toNumber(value any) (float64, bool) {
+	switch v := value.(type) {
+	case nil:
+		return 0, true
+	case float64:
+		return v, true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		s := strings.TrimSpace(v)
+		if s == "" {
+			return 0, true
+		}
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return math.NaN(), true
+		}
+		return f, true
+	default:
+		return 0, false
+	}
+}
+
+func looseEqual(left, right any) bool {
+	if left == nil || right == nil {
+		return left == nil && right == nil
+	}
+
+	ls, lok := left.(string)
+	rs, rok := right.(string)
+	if lok && rok {
+		return ls == rs
+	}
+
+	l, lok := toNumber(left)
+	r, rok := toNumber(right)
+	if lok && rok {
+		return l == r
+	}
+
+	return false
+}
+
+func strictEqual(left, right any) bool {
+	switch l := left.(type) {
+	case nil:
+		return right == nil
+	case float64:
+		r, ok := right.(float64)
+		return ok && l == r
+	case string:
+		r, ok := right.(string)
+		return ok && l == r
+	case bool:
+		r, ok := right.(bool)
+		return ok && l == r
+	default:
+		return false
+	}
+}
+
+func conditionString(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenIdent
+	tokenOperator
+)
+
+type conditionToken struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+type conditionParser struct {
+	source string
+	tokens []conditionToken
+	pos    int
+}
+
+var conditionOperators = []string{"===", "!==", "==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "+", "-", "*", "/", "%", "(", ")", ".", "[", "]"}
+
+var conditionPrecedence = map[string]int{
+	"||":  1,
+	"&&":  2,
+	"==":  3,
+	"!=":  3,
+	"===": 3,
+	"!==": 3,
+	"<":   4,
+	"<=":  4,
+	">":   4,
+	">=":  4,
+	"+":   5,
+	"-":   5,
+	"*":   6,
+	"/":   6,
+	"%":   6,
+}
+
+func (p *conditionParser) errorf(tok conditionToken, format string, args ...any) error {
+	return fmt.Errorf("goplatform: condition: at %d: %s", tok.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *conditionParser) tokenize() error {
+	s := p.source
+	i := 0
+
+outer:
+	for i < len(s) {
+		c := rune(s[i])
+
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c >= '0' && c <= '9':
+			start := i
+			for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.' || s[i] == 'e' || s[i] == 'E' ||
+				(s[i] == '-' || s[i] == '+') && (s[i-1] == 'e' || s[i-1] == 'E')) {
+				i++
+			}
+			p.tokens = append(p.tokens, conditionToken{kind: tokenNumber, text: s[start:i], pos: start})
+		case c == '\'' || c == '"':
+			start := i
+			i++
+			var b strings.Builder
+			for i < len(s) && rune(s[i]) != c {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				b.WriteByte(s[i])
+				i++
+			}
+			if i >= len(s) {
+				return fmt.Errorf("goplatform: condition: at %d: unterminated string", start)
+			}
+			i++
+			p.tokens = append(p.tokens, conditionToken{kind: tokenString, text: b.String(), pos: start})
+		case c == '_' || c == '$' || unicode.IsLetter(c):
+			start := i
+			for i < len(s) && (s[i] == '_' || s[i] == '$' || unicode.IsLetter(rune(s[i])) || unicode.IsDigit(rune(s[i]))) {
+				i++
+			}
+			p.tokens = append(p.tokens, conditionToken{kind: tokenIdent, text: s[start:i], pos: start})
+		default:
+			for _, op := range conditionOperators {
+				if strings.HasPrefix(s[i:], op) {
+					p.tokens = append(p.tokens, conditionToken{kind: tokenOperator, text: op, pos: i})
+					i += len(op)
+					continue outer
+				}
+			}
+			return fmt.Errorf("goplatform: condition: at %d: unexpected character %q", i, c)
+		}
+	}
+
+	p.tokens = append(p.tokens, conditionToken{kind: tokenEOF, pos: len(s)})
+	return nil
+}
+
+func (p *conditionParser) peek() conditionToken {
+	return p.tokens[p.pos]
+}
+
+func (p *conditionParser) next() conditionToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *conditionParser) expect(op string) error {
+	tok := p.next()
+	if tok.kind != tokenOperator || tok.text != op {
+		if tok.kind == tokenEOF {
+			return p.errorf(tok, "expected %q, got end of expression", op)
+		}
+		return p.errorf(tok, "expected %q, got %q", op, tok.text)
+	}
+	return nil
+}
+
+func (p *conditionParser) parseExpression(minPrecedence int) (conditionNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		tok := p.peek()
+		precedence, ok := conditionPrecedence[tok.text]
+		if tok.kind != tokenOperator || !ok || precedence <= minPrecedence {
+			return left, nil
+		}
+		p.next()
+
+		right, err := p.parseExpression(precedence)
+		if err != nil {
+			return nil, err
+		}
+		left = conditionBinary{op: tok.text, left: left, right: right}
+	}
+}
+
+func (p *conditionParser) parseUnary() (conditionNode, error) {
+	tok := p.peek()
+	if tok.kind == tokenOperator && (tok.text == "!" || tok.text == "-") {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return conditionUnary{op: tok.text, operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *conditionParser) parsePrimary() (conditionNode, error) {
+	tok := p.next()
+
+	switch tok.kind {
+	case tokenNumber:
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, p.errorf(tok, "invalid number %q", tok.text)
+		}
+		return conditionLiteral{value: f}, nil
+	case tokenString:
+		return conditionLiteral{value: tok.text}, nil
+	case tokenIdent:
+		switch tok.text {
+		case "true":
+			return conditionLiteral{value: true}, nil
+		case "false":
+			return conditionLiteral{value: false}, nil
+		case "null", "undefined":
+			return conditionLiteral{value: nil}, nil
+		}
+		return p.parseRef(tok)
+	case tokenOperator:
+		if tok.text == "(" {
+			node, err := p.parseExpression(0)
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return node, nil
+		}
+		return nil, p.errorf(tok, "unexpected %q", tok.text)
+	default:
+		return nil, p.errorf(tok, "unexpected end of expression")
+	}
+}
+
+func (p *conditionParser) parseRef(alias conditionToken) (conditionNode, error) {
+	tok := p.next()
+	if tok.kind != tokenOperator || (tok.text != "." && tok.text != "[") {
+		return nil, p.errorf(alias, "expected a property of %q", alias.text)
+	}
+
+	if tok.text == "." {
+		prop := p.next()
+		if prop.kind != tokenIdent {
+			return nil, p.errorf(prop, "expected a property name after %q", alias.text+".")
+		}
+		return conditionRef{alias: alias.text, property: prop.text}, nil
+	}
+
+	prop := p.next()
+	if prop.kind != tokenString {
+		return nil, p.errorf(prop, "expected a quoted property name after %q", alias.text+"[")
+	}
+	if err := p.expect("]"); err != nil {
+		return nil, err
+	}
+	return conditionRef{alias: alias.text, property: prop.text}, nil
+}
@@ -0,0 +1,260 @@
+package goplatform
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type RuleFileFormat string
+
+const (
+	RULE_FILE_YAML RuleFileFormat = "yaml"
+	RULE_FILE_JSON RuleFileFormat = "json"
+)
+
+// ruleServerFields are managed by the platform and never written to files.
+var ruleServerFields = []string{"projectId", "createdAt", "updatedAt", "_id", "__v"}
+
+// MarshalRuleFile encodes rule in a canonical, human-editable form: keys are
+// sorted and server-managed fields are stripped.
+func MarshalRuleFile(rule Rule, format RuleFileFormat) ([]byte, error) {
+	b, err := json.Marshal(rule)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc map[string]any
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	for _, field := range ruleServerFields {
+		delete(doc, field)
+	}
+
+	switch format {
+	case RULE_FILE_YAML, "":
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(doc); err != nil {
+			return nil, err
+		}
+		if err := enc.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case RULE_FILE_JSON:
+		b, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(b, '\n'), nil
+	default:
+		return nil, fmt.Errorf("goplatform: unsupported rule file format %q", format)
+	}
+}
+
+// UnmarshalRuleFile decodes and validates a rule written by MarshalRuleFile.
+func UnmarshalRuleFile(data []byte, format RuleFileFormat) (Rule, error) {
+	var zero Rule
+
+	var doc map[string]any
+	switch format {
+	case RULE_FILE_YAML, "":
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return zero, err
+		}
+	case RULE_FILE_JSON:
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return zero, err
+		}
+	default:
+		return zero, fmt.Errorf("goplatform: unsupported rule file format %q", format)
+	}
+
+	for _, field := range ruleServerFields {
+		delete(doc, field)
+	}
+
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return zero, err
+	}
+
+	var rule Rule
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&rule); err != nil {
+		return zero, err
+	}
+
+	if err := rule.Validate(); err != nil {
+		return zero, err
+	}
+
+	return rule, nil
+}
+
+// ExportRules writes every rule of project to dir, one file per rule named
+// after the rule. Files holding an exported rule under another name, e.g.
+// after a rename, are removed; files of rules no longer in the project are
+// left untouched.
+func ExportRules(ctx context.Context, project Project, dir string, format ...RuleFileFormat) error {
+	_format := RULE_FILE_YAML
+	if len(format) > 0 {
+		_format = format[0]
+	}
+
+	rules, err := project.GetRules(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return err
+	}
+
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].Uuid < rules[j].Uuid
+	})
+
+	used := map[string]bool{}
+	paths := map[string]string{}
+	for _, rule := range rules {
+		b, err := MarshalRuleFile(rule, _format)
+		if err != nil {
+			return fmt.Errorf("goplatform: rule %s: %w", rule.Uuid, err)
+		}
+
+		name := ruleFileName(rule)
+		if used[name] {
+			name = fmt.Sprintf("%s-%s", name, rule.Uuid)
+		}
+		used[name] = true
+
+		path := filepath.Join(dir, name+"."+string(_format))
+		if err := os.WriteFile(path, b, 0o600); err != nil {
+			return err
+		}
+		paths[rule.Uuid] = path
+	}
+
+	return removeStaleRuleFiles(dir, paths)
+}
+
+// removeStaleRuleFiles removes the rule files in dir holding a rule of paths
+// at a different path. Files that cannot be decoded are left untouched.
+func removeStaleRuleFiles(dir string, paths map[string]string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		format, ok := ruleFileFormat(entry)
+		if !ok {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		rule, err := UnmarshalRuleFile(b, format)
+		if err != nil {
+			continue
+		}
+		if exported, ok := paths[rule.Uuid]; ok && exported != path {
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// ImportRules reads and validates every .yaml, .yml and .json rule file in
+// dir, sorted by file name. Errors of all files are reported together.
+func ImportRules(dir string) ([]Rule, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []Rule
+	var errs []error
+	for _, entry := range entries {
+		format, ok := ruleFileFormat(entry)
+		if !ok {
+			continue
+		}
+
+		b, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		rule, err := UnmarshalRuleFile(b, format)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", entry.Name(), err))
+			continue
+		}
+		rules = append(rules, rule)
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return rules, nil
+}
+
+// ruleFileFormat returns the format of a rule file from its extension.
+func ruleFileFormat(entry os.DirEntry) (RuleFileFormat, bool) {
+	if entry.IsDir() {
+		return "", false
+	}
+
+	switch strings.ToLower(filepath.Ext(entry.Name())) {
+	case ".yaml", ".yml":
+		return RULE_FILE_YAML, true
+	case ".json":
+		return RULE_FILE_JSON, true
+	default:
+		return "", false
+	}
+}
+
+// ruleFileName turns the rule name into a lowercase, dash separated slug,
+// falling back to the rule UUID.
+func ruleFileName(rule Rule) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(rule.Name) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+
+	name := strings.TrimSuffix(b.String(), "-")
+	if name == "" {
+		return rule.Uuid
+	}
+	return name
+}
@@ -0,0 +1,267 @@
+package goplatform
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"strings"
+	"time"
+)
+
+const (
+	RULE_BRANCH_ACTIONS      = "actions"
+	RULE_BRANCH_ELSE_ACTIONS = "elseActions"
+)
+
+// RuleSimulationInput describes the trigger fired in a rule dry-run.
+type RuleSimulationInput struct {
+	// Trigger describes what happened: a property change on DeviceId and
+	// Property, a tick of Crontab or a message on Topic.
+	Trigger RuleTrigger
+	// Value is the new property value or the topic message.
+	Value any
+	// At is when the trigger fires. It is only used by SimulateSequence.
+	At time.Time
+	// States are the device states keyed by device UUID, used to evaluate
+	// the rule condition. The triggering property change is applied on top.
+	States map[string]map[string]any
+	// Running reports whether an execution of the rule is still in progress.
+	Running bool
+	// Runtime is the runtime executing the rule, edge or cloud. When set,
+	// rules in a different mode are skipped.
+	Runtime string
+}
+
+// RuleTrace is the structured outcome of a rule dry-run.
+type RuleTrace struct {
+	RuleId     string          `json:"ruleId"`
+	Mode       string          `json:"mode"`
+	Executed   bool            `json:"executed"`
+	Skipped    string          `json:"skipped,omitempty"`
+	Trigger    *RuleTrigger    `json:"trigger,omitempty"`
+	Condition  *bool           `json:"condition,omitempty"`
+	Branch     string          `json:"branch,omitempty"`
+	Steps      []RuleTraceStep `json:"steps"`
+	DurationMs int64           `json:"durationMs"`
+}
+
+// RuleTraceStep is an action that would be executed, OffsetMs milliseconds
+// after the trigger.
+type RuleTraceStep struct {
+	Index    int             `json:"index"`
+	Type     string          `json:"type"`
+	OffsetMs int64           `json:"offsetMs"`
+	DelayMs  int64           `json:"delayMs,omitempty"`
+	Log      *string         `json:"log,omitempty"`
+	Webhook  *RuleWebhook    `json:"webhook,omitempty"`
+	Command  *CommandRequest `json:"command,omitempty"`
+	Wasm     *string         `json:"wasm,omitempty"`
+}
+
+// RuleWebhook is the request a webhook action would send.
+type RuleWebhook struct {
+	Uri     string `json:"uri"`
+	Payload string `json:"payload,omitempty"`
+}
+
+// Simulate dry-runs the rule for input: it matches the trigger, evaluates the
+// condition and returns the ordered list of actions that would execute.
+// Delay times are expressed in milliseconds.
+func (r Rule) Simulate(input RuleSimulationInput) (RuleTrace, error) {
+	trace := RuleTrace{
+		RuleId: r.Uuid,
+		Mode:   r.Mode,
+		Steps:  []RuleTraceStep{},
+	}
+
+	switch {
+	case r.Status == RULE_STATUS_DISABLED:
+		trace.Skipped = "rule is disabled"
+		return trace, nil
+	case input.Runtime != "" && r.Mode != "" && input.Runtime != r.Mode:
+		trace.Skipped = fmt.Sprintf("rule runs in %s mode", r.Mode)
+		return trace, nil
+	}
+
+	trigger, ok := r.matchTrigger(input.Trigger)
+	if !ok {
+		trace.Skipped = "no matching trigger"
+		return trace, nil
+	}
+	trace.Trigger = &trigger
+
+	if input.Running && !r.AllowConcurrent {
+		trace.Skipped = "concurrent execution not allowed"
+		return trace, nil
+	}
+
+	trace.Branch = RULE_BRANCH_ACTIONS
+	actions := r.Actions
+
+	if r.Condition != nil && strings.TrimSpace(r.Condition.Condition) != "" {
+		states := map[string]map[string]any{}
+		for id, state := range input.States {
+			states[id] = maps.Clone(state)
+		}
+		if trigger.Type == RULE_TRIGGER_TELEMETRY && trigger.DeviceId != nil && trigger.Property != nil {
+			if states[*trigger.DeviceId] == nil {
+				states[*trigger.DeviceId] = map[string]any{}
+			}
+			states[*trigger.DeviceId][*trigger.Property] = input.Value
+		}
+
+		res, err := r.Condition.Evaluate(states)
+		if err != nil {
+			return trace, err
+		}
+		trace.Condition = &res
+
+		if !res {
+			trace.Branch = RULE_BRANCH_ELSE_ACTIONS
+			actions = r.ElseActions
+		}
+	}
+
+	var offset int64
+	for i, action := range actions {
+		step, err := simulateAction(i, action, offset)
+		if err != nil {
+			return trace, err
+		}
+		offset += step.DelayMs
+		trace.Steps = append(trace.Steps, step)
+	}
+
+	trace.Executed = true
+	trace.DurationMs = offset
+
+	return trace, nil
+}
+
+// SimulateSequence dry-runs the rule for each input in order, deriving
+// Running from the duration of the previous executions.
+func (r Rule) SimulateSequence(inputs []RuleSimulationInput) ([]RuleTrace, error) {
+	var busyUntil time.Time
+
+	traces := make([]RuleTrace, 0, len(inputs))
+	for _, input := range inputs {
+		input.Running = input.At.Before(busyUntil)
+
+		trace, err := r.Simulate(input)
+		if err != nil {
+			return traces, err
+		}
+
+		if trace.Executed {
+			end := input.At.Add(time.Duration(trace.DurationMs) * time.Millisecond)
+			if end.After(busyUntil) {
+				busyUntil = end
+			}
+		}
+
+		traces = append(traces, trace)
+	}
+
+	return traces, nil
+}
+
+func (r Rule) matchTrigger(input RuleTrigger) (RuleTrigger, bool) {
+	for _, trigger := range r.Triggers {
+		if trigger.Type != input.Type {
+			continue
+		}
+
+		switch trigger.Type {
+		case RULE_TRIGGER_TELEMETRY:
+			if equalPtr(trigger.DeviceId, input.DeviceId) && (trigger.Property == nil || equalPtr(trigger.Property, input.Property)) {
+				return trigger, true
+			}
+		case RULE_TRIGGER_CRON:
+			if input.Crontab == nil || equalPtr(trigger.Crontab, input.Crontab) {
+				return trigger, true
+			}
+		case RULE_TRIGGER_TOPIC:
+			if trigger.Topic != nil && input.Topic != nil && matchTopic(*trigger.Topic, *input.Topic) {
+				return trigger, true
+			}
+		default:
+			return trigger, true
+		}
+	}
+
+	var zero RuleTrigger
+	return zero, false
+}
+
+func simulateAction(index int, action RuleAction, offset int64) (RuleTraceStep, error) {
+	step := RuleTraceStep{
+		Index:    index,
+		Type:     action.Type,
+		OffsetMs: offset,
+	}
+
+	switch action.Type {
+	case RULE_ACTION_DELAY:
+		if action.Time == nil || *action.Time < 0 {
+			return step, fmt.Errorf("goplatform: rule: action %d: invalid delay", index)
+		}
+		step.DelayMs = *action.Time
+	case RULE_ACTION_LOG:
+		step.Log = action.Value
+	case RULE_ACTION_WEBHOOK:
+		if action.Uri == nil {
+			return step, fmt.Errorf("goplatform: rule: action %d: missing webhook uri", index)
+		}
+		step.Webhook = &RuleWebhook{Uri: *action.Uri}
+		if action.Payload != nil {
+			step.Webhook.Payload = *action.Payload
+		}
+	case RULE_ACTION_COMMAND:
+		b, err := json.Marshal(action.Command)
+		if err != nil {
+			return step, err
+		}
+		var cmd CommandRequest
+		if err := json.Unmarshal(b, &cmd); err != nil {
+			return step, fmt.Errorf("goplatform: rule: action %d: %w", index, err)
+		}
+		if cmd.DeviceId == nil && action.DeviceId != nil {
+			cmd.DeviceId = action.DeviceId
+		}
+		step.Command = &cmd
+	case RULE_ACTION_WASM:
+		step.Wasm = action.Label
+	default:
+		return step, fmt.Errorf("goplatform: rule: action %d: unknown type %q", index, action.Type)
+	}
+
+	return step, nil
+}
+
+// matchTopic matches topic against an MQTT style filter, supporting the +
+// and # wildcards.
+func matchTopic(filter, topic string) bool {
+	f := strings.Split(filter, "/")
+	t := strings.Split(topic, "/")
+
+	for i, level := range f {
+		if level == "#" {
+			return true
+		}
+		if i >= len(t) {
+			return false
+		}
+		if level != "+" && level != t[i] {
+			return false
+		}
+	}
+
+	return len(f) == len(t)
+}
+
+func equalPtr[T comparable](a, b *T) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,150 @@
+package goplatform
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// RuleTriggerVariant is the typed form of a RuleTrigger. It is implemented by
+// PropertyRuleTrigger, CronRuleTrigger and TopicRuleTrigger.
+type RuleTriggerVariant interface {
+	Trigger() RuleTrigger
+}
+
+type PropertyRuleTrigger struct {
+	DeviceId string
+	Property string
+}
+
+type CronRuleTrigger struct {
+	Crontab string
+}
+
+type TopicRuleTrigger struct {
+	Topic string
+}
+
+// PropertyTrigger returns a trigger firing when property of deviceId changes.
+func PropertyTrigger(deviceId, property string) RuleTrigger {
+	return PropertyRuleTrigger{DeviceId: deviceId, Property: property}.Trigger()
+}
+
+func CronTrigger(crontab string) RuleTrigger {
+	return CronRuleTrigger{Crontab: crontab}.Trigger()
+}
+
+func TopicTrigger(topic string) RuleTrigger {
+	return TopicRuleTrigger{Topic: topic}.Trigger()
+}
+
+func (t PropertyRuleTrigger) Trigger() RuleTrigger {
+	return RuleTrigger{Type: RULE_TRIGGER_TELEMETRY, DeviceId: &t.DeviceId, Property: &t.Property}
+}
+
+func (t CronRuleTrigger) Trigger() RuleTrigger {
+	return RuleTrigger{Type: RULE_TRIGGER_CRON, Crontab: &t.Crontab}
+}
+
+func (t TopicRuleTrigger) Trigger() RuleTrigger {
+	return RuleTrigger{Type: RULE_TRIGGER_TOPIC, Topic: &t.Topic}
+}
+
+// Variant returns the typed form of the trigger, to be inspected with a type
+// switch.
+func (t RuleTrigger) Variant() (RuleTriggerVariant, error) {
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+
+	switch t.Type {
+	case RULE_TRIGGER_TELEMETRY:
+		return PropertyRuleTrigger{DeviceId: *t.DeviceId, Property: *t.Property}, nil
+	case RULE_TRIGGER_CRON:
+		return CronRuleTrigger{Crontab: *t.Crontab}, nil
+	default:
+		return TopicRuleTrigger{Topic: *t.Topic}, nil
+	}
+}
+
+// Validate reports the fields that are missing or irrelevant for the trigger
+// type.
+func (t RuleTrigger) Validate() error {
+	fields := map[string]bool{
+		"deviceId": t.DeviceId != nil,
+		"property": t.Property != nil,
+		"crontab":  t.Crontab != nil,
+		"topic":    t.Topic != nil,
+	}
+
+	var allowed []string
+	var errs []error
+
+	switch t.Type {
+	case RULE_TRIGGER_TELEMETRY:
+		allowed = []string{"deviceId", "property"}
+		if deref(t.DeviceId) == "" {
+			errs = append(errs, errors.New("missing deviceId"))
+		}
+		if deref(t.Property) == "" {
+			errs = append(errs, errors.New("missing property"))
+		}
+	case RULE_TRIGGER_CRON:
+		allowed = []string{"crontab"}
+		if strings.TrimSpace(deref(t.Crontab)) == "" {
+			errs = append(errs, errors.New("missing crontab"))
+		} else if _, err := ParseCrontab(*t.Crontab); err != nil {
+			errs = append(errs, err)
+		}
+	case RULE_TRIGGER_TOPIC:
+		allowed = []string{"topic"}
+		if deref(t.Topic) == "" {
+			errs = append(errs, errors.New("missing topic"))
+		}
+	default:
+		return fmt.Errorf("goplatform: rule trigger: unknown type %q", t.Type)
+	}
+
+	errs = append(errs, irrelevantFields(fields, allowed)...)
+	if len(errs) > 0 {
+		return fmt.Errorf("goplatform: rule trigger %s: %w", t.Type, errors.Join(errs...))
+	}
+
+	return nil
+}
+
+// Validate checks the triggers, actions and condition of the rule.
+func (r Rule) Validate() error {
+	var errs []error
+
+	if strings.TrimSpace(r.Name) == "" {
+		errs = append(errs, errors.New("goplatform: rule: missing name"))
+	}
+	if len(r.Triggers) == 0 {
+		errs = append(errs, errors.New("goplatform: rule: missing triggers"))
+	}
+
+	for i, trigger := range r.Triggers {
+		if err := trigger.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("triggers[%d]: %w", i, err))
+		}
+	}
+	for i, action := range r.Actions {
+		if err := action.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("actions[%d]: %w", i, err))
+		}
+	}
+	for i, action := range r.ElseActions {
+		if err := action.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("elseActions[%d]: %w", i, err))
+		}
+	}
+
+	if r.Condition != nil && strings.TrimSpace(r.Condition.Condition) != "" {
+		if _, err := r.Condition.Compile(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
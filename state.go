@@ -0,0 +1,292 @@
+package goplatform
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+const (
+	PROPERTY_TYPE_INTEGER = "integer"
+	PROPERTY_TYPE_FLOAT   = "float"
+	PROPERTY_TYPE_DOUBLE  = "double"
+	PROPERTY_TYPE_BOOLEAN = "boolean"
+	PROPERTY_TYPE_STRING  = "string"
+)
+
+// StateValue returns the current value of the state property name. When the
+// device embeds its device type, the value is coerced to the property type:
+// int64 for integer, float64 for float and double, bool for boolean and
+// string for string. Values that cannot be coerced are returned as they are.
+func (d Device) StateValue(name string) (any, bool) {
+	value, ok := d.State[name]
+	if !ok {
+		return nil, false
+	}
+
+	if d.DeviceType != nil {
+		if coerced, err := d.DeviceType.CoerceProperty(name, value); err == nil {
+			return coerced, true
+		}
+	}
+
+	return value, true
+}
+
+// StateTime returns when the state property name was last updated.
+func (d Device) StateTime(name string) (time.Time, bool) {
+	t, ok := d.StateUpdatedAt[name]
+	return t, ok
+}
+
+// StateAs returns the state property name of device converted to T. Numbers,
+// booleans and strings are converted between each other when lossless, any
+// other T is decoded from the JSON form of the value.
+func StateAs[T any](device Device, name string) (T, error) {
+	var res T
+
+	value, ok := device.StateValue(name)
+	if !ok {
+		return res, fmt.Errorf("goplatform: device %s: state property %s not found", device.Uuid, name)
+	}
+
+	if v, ok := value.(T); ok {
+		return v, nil
+	}
+
+	var err error
+	switch p := any(&res).(type) {
+	case *int:
+		var n int64
+		n, err = toInt64(value)
+		*p = int(n)
+	case *int64:
+		*p, err = toInt64(value)
+	case *float64:
+		*p, err = toFloat(value)
+	case *bool:
+		var b bool
+		b, ok = toBool(value)
+		if !ok {
+			err = fmt.Errorf("not a boolean: %v", value)
+		}
+		*p = b
+	case *string:
+		*p = toString(value)
+	default:
+		var b []byte
+		if b, err = json.Marshal(value); err == nil {
+			err = json.Unmarshal(b, &res)
+		}
+	}
+
+	if err != nil {
+		var zero T
+		return zero, fmt.Errorf("goplatform: device %s: state property %s: %w", device.Uuid, name, err)
+	}
+
+	return res, nil
+}
+
+// CoerceProperty converts value to the Go type matching the type of the
+// property name. Properties without a known type are returned unchanged.
+func (dt DeviceType) CoerceProperty(name string, value any) (any, error) {
+	prop, ok := dt.Properties[name]
+	if !ok || value == nil {
+		return value, nil
+	}
+
+	res, err := coerceValue(prop.Type, value)
+	if err != nil {
+		return nil, fmt.Errorf("goplatform: property %s: %w", name, err)
+	}
+
+	return res, nil
+}
+
+// coerceValue converts value to the Go type of a property type, returning
+// values of unknown types unchanged.
+func coerceValue(typ string, value any) (any, error) {
+	switch typ {
+	case PROPERTY_TYPE_INTEGER:
+		return toInt64(value)
+	case PROPERTY_TYPE_FLOAT, PROPERTY_TYPE_DOUBLE:
+		return toFloat(value)
+	case PROPERTY_TYPE_BOOLEAN:
+		b, ok := toBool(value)
+		if !ok {
+			return nil, fmt.Errorf("not a boolean: %v", value)
+		}
+		return b, nil
+	case PROPERTY_TYPE_STRING:
+		return toString(value), nil
+	default:
+		return value, nil
+	}
+}
+
+func (p *DeviceTypeProperty) UnmarshalJSON(data []byte) error {
+	type property DeviceTypeProperty
+	if err := json.Unmarshal(data, (*property)(p)); err != nil {
+		return err
+	}
+
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	for _, key := range []string{"uom", "description", "displayName", "type", "validation", "aggregations"} {
+		delete(raw, key)
+	}
+
+	p.Extras = nil
+	if len(raw) > 0 {
+		p.Extras = raw
+	}
+
+	return nil
+}
+
+func (p DeviceTypeProperty) MarshalJSON() ([]byte, error) {
+	type property DeviceTypeProperty
+	b, err := json.Marshal(property(p))
+	if err != nil || len(p.Extras) == 0 {
+		return b, err
+	}
+
+	var data map[string]any
+	if err := json.Unmarshal(b, &data); err != nil {
+		return nil, err
+	}
+	for key, value := range p.Extras {
+		if _, ok := data[key]; !ok {
+			data[key] = value
+		}
+	}
+
+	return json.Marshal(data)
+}
+
+func (d *Device) UnmarshalJSON(data []byte) error {
+	type device Device
+	var raw struct {
+		*device
+		StateUpdatedAt map[string]any `json:"stateUpdatedAt"`
+	}
+	raw.device = (*device)(d)
+
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	d.StateUpdatedAt = nil
+	if raw.StateUpdatedAt != nil {
+		d.StateUpdatedAt = make(map[string]time.Time, len(raw.StateUpdatedAt))
+		for name, value := range raw.StateUpdatedAt {
+			if t, ok := parseTimestamp(value); ok {
+				d.StateUpdatedAt[name] = t
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseTimestamp reads the timestamps sent by the platform: RFC 3339 strings
+// and epoch numbers in seconds, milliseconds, microseconds or nanoseconds.
+// Microsecond epochs rendered as milliseconds, which show up as dates far
+// in the future like +056576-05-05T09:27:59.181Z, are converted back.
+func parseTimestamp(value any) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, !v.IsZero()
+	case string:
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return t, true
+		}
+		t, ok := parseExtendedYear(v)
+		if !ok {
+			return time.Time{}, false
+		}
+		return time.UnixMicro(t.UnixMilli()).UTC(), true
+	default:
+		f, ok := toFloat64(value)
+		if !ok || f <= 0 {
+			return time.Time{}, false
+		}
+		n := int64(f)
+		switch {
+		case n < 1e11:
+			return time.Unix(n, 0).UTC(), true
+		case n < 1e14:
+			return time.UnixMilli(n).UTC(), true
+		case n < 1e17:
+			return time.UnixMicro(n).UTC(), true
+		default:
+			return time.Unix(0, n).UTC(), true
+		}
+	}
+}
+
+// parseExtendedYear parses the ISO 8601 expanded year form of JavaScript
+// dates beyond year 9999, e.g. +056576-05-05T09:27:59.181Z.
+func parseExtendedYear(s string) (time.Time, bool) {
+	if len(s) < 8 || (s[0] != '+' && s[0] != '-') || s[7] != '-' {
+		return time.Time{}, false
+	}
+
+	year, err := strconv.Atoi(s[1:7])
+	if err != nil {
+		return time.Time{}, false
+	}
+	if s[0] == '-' {
+		year = -year
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, "2000"+s[7:])
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return t.AddDate(year-2000, 0, 0), true
+}
+
+func toInt64(value any) (int64, error) {
+	if s, ok := value.(string); ok {
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n, nil
+		}
+	}
+
+	f, ok := toFloat64(value)
+	if !ok {
+		return 0, fmt.Errorf("not an integer: %v", value)
+	}
+	if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+		return 0, fmt.Errorf("not an integer: %v", value)
+	}
+
+	return int64(f), nil
+}
+
+func toFloat(value any) (float64, error) {
+	f, ok := toFloat64(value)
+	if !ok {
+		return 0, fmt.Errorf("not a number: %v", value)
+	}
+
+	return f, nil
+}
+
+func toString(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,289 @@
+package goplatform
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type NotificationType string
+
+const (
+	NOTIFICATION_STATE   NotificationType = "state"
+	NOTIFICATION_EVENT   NotificationType = "event"
+	NOTIFICATION_COMMAND NotificationType = "command"
+)
+
+type OverflowPolicy string
+
+const (
+	// OVERFLOW_BLOCK stops reading the stream until the consumer catches up.
+	OVERFLOW_BLOCK OverflowPolicy = "block"
+	// OVERFLOW_DROP_OLDEST discards the oldest buffered notification.
+	OVERFLOW_DROP_OLDEST OverflowPolicy = "dropOldest"
+)
+
+// Notification is a change pushed by the platform: the new State of DeviceId,
+// a new Event or a Command status transition.
+type Notification struct {
+	// Id is the stream position of the notification, used to resume.
+	Id       string           `json:"-"`
+	Type     NotificationType `json:"type"`
+	DeviceId string           `json:"deviceId,omitempty"`
+	State    map[string]any   `json:"state,omitempty"`
+	Event    *Event           `json:"event,omitempty"`
+	Command  *Command         `json:"command,omitempty"`
+}
+
+type SubscriptionOptions struct {
+	// Types filters the notifications, all types when empty.
+	Types []NotificationType
+	// DeviceIds filters the notifications of the given devices, all devices
+	// when empty. Notifications without a device are dropped when set.
+	DeviceIds []string
+	// LastEventId resumes the stream after the given notification Id.
+	LastEventId string
+	// BufferSize is the capacity of the notification channel, 64 by default.
+	BufferSize int
+	// Overflow is the policy applied when the channel is full, OVERFLOW_BLOCK
+	// by default.
+	Overflow OverflowPolicy
+	// ReconnectDelay is the initial delay between reconnections, doubled up
+	// to MaxReconnectDelay. Defaults to 1s and 30s.
+	ReconnectDelay    time.Duration
+	MaxReconnectDelay time.Duration
+	// OnError is called with the errors that caused a reconnection.
+	OnError func(error)
+}
+
+type subscription struct {
+	project Project
+	options SubscriptionOptions
+	lastId  string
+	delay   time.Duration
+}
+
+// Subscribe opens a Server-Sent Events stream of the project notifications.
+// The stream reconnects automatically, resuming from the last received
+// notification, until ctx is canceled or the platform rejects the
+// subscription; the channel is closed then.
+func (p Project) Subscribe(ctx context.Context, options SubscriptionOptions) (<-chan Notification, error) {
+	if options.BufferSize <= 0 {
+		options.BufferSize = 64
+	}
+	if options.Overflow == "" {
+		options.Overflow = OVERFLOW_BLOCK
+	}
+	if options.ReconnectDelay <= 0 {
+		options.ReconnectDelay = time.Second
+	}
+	if options.MaxReconnectDelay < options.ReconnectDelay {
+		options.MaxReconnectDelay = max(30*time.Second, options.ReconnectDelay)
+	}
+
+	s := &subscription{
+		project: p,
+		options: options,
+		lastId:  options.LastEventId,
+		delay:   options.ReconnectDelay,
+	}
+
+	body, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan Notification, options.BufferSize)
+	go s.run(ctx, body, out)
+
+	return out, nil
+}
+
+func (s *subscription) connect(ctx context.Context) (io.ReadCloser, error) {
+	req, err := s.project.platformRef.newRequest(ctx, httpGet, nil, "projects", s.project.Uuid, "subscribe")
+	if err != nil {
+		return nil, err
+	}
+
+	query := url.Values{}
+	if len(s.options.Types) > 0 {
+		types := make([]string, len(s.options.Types))
+		for i, t := range s.options.Types {
+			types[i] = string(t)
+		}
+		query.Set("types", strings.Join(types, ","))
+	}
+	if len(s.options.DeviceIds) > 0 {
+		query.Set("deviceIds", strings.Join(s.options.DeviceIds, ","))
+	}
+	req.URL.RawQuery = query.Encode()
+
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+	if s.lastId != "" {
+		req.Header.Set("Last-Event-ID", s.lastId)
+	}
+
+	resp, err := s.project.platformRef.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close() //nolint:errcheck
+		return nil, newAPIError(resp)
+	}
+
+	return resp.Body, nil
+}
+
+func (s *subscription) run(ctx context.Context, body io.ReadCloser, out chan Notification) {
+	defer close(out)
+
+	for {
+		err := s.read(ctx, body, out)
+		body.Close() //nolint:errcheck
+		if ctx.Err() != nil {
+			return
+		}
+		s.report(fmt.Errorf("goplatform: subscription: %w", err))
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(s.delay):
+			}
+			s.delay = min(2*s.delay, s.options.MaxReconnectDelay)
+
+			body, err = s.connect(ctx)
+			if err == nil {
+				break
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			s.report(err)
+
+			var apiErr *APIError
+			if errors.As(err, &apiErr) && !retryableStatus(apiErr.StatusCode) {
+				return
+			}
+		}
+	}
+}
+
+// read dispatches the events of an SSE stream until it ends.
+func (s *subscription) read(ctx context.Context, body io.Reader, out chan Notification) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var id, event string
+	var data []string
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if line == "" {
+			if len(data) > 0 {
+				if err := s.dispatch(ctx, id, event, strings.Join(data, "\n"), out); err != nil {
+					return err
+				}
+			}
+			event, data = "", nil
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+
+		switch field {
+		case "":
+			// Comment, used as keep-alive
+		case "id":
+			id = value
+		case "event":
+			event = value
+		case "data":
+			data = append(data, value)
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil && ms > 0 {
+				s.options.ReconnectDelay = time.Duration(ms) * time.Millisecond
+				s.delay = s.options.ReconnectDelay
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return io.ErrUnexpectedEOF
+}
+
+func (s *subscription) dispatch(ctx context.Context, id, event, data string, out chan Notification) error {
+	var n Notification
+	if err := json.Unmarshal([]byte(data), &n); err != nil {
+		s.report(fmt.Errorf("goplatform: subscription: notification %s: %w", id, err))
+		return nil
+	}
+	if n.Type == "" {
+		n.Type = NotificationType(event)
+	}
+	n.Id = id
+
+	// A notification was received, the connection is healthy again
+	s.delay = s.options.ReconnectDelay
+	if id != "" {
+		s.lastId = id
+	}
+
+	if len(s.options.Types) > 0 && !slices.Contains(s.options.Types, n.Type) {
+		return nil
+	}
+	// The platform may ignore the deviceIds parameter
+	if len(s.options.DeviceIds) > 0 && !slices.Contains(s.options.DeviceIds, n.DeviceId) {
+		return nil
+	}
+
+	if s.options.Overflow == OVERFLOW_DROP_OLDEST {
+		for {
+			select {
+			case out <- n:
+				return nil
+			default:
+			}
+			select {
+			case <-out:
+			default:
+			}
+		}
+	}
+
+	select {
+	case out <- n:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *subscription) report(err error) {
+	if s.options.OnError != nil {
+		s.options.OnError(err)
+	}
+}
+
+// retryableStatus reports whether a request failed with status may succeed
+// when retried.
+func retryableStatus(status int) bool {
+	return status >= 500 || status == http.StatusRequestTimeout || status == http.StatusTooManyRequests
+}
@@ -0,0 +1,80 @@
+package goplatform_test
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ApioIoT/goplatform/v2"
+)
+
+func TestConnectivity(t *testing.T) {
+	t.Run("Node", func(t *testing.T) {
+		b, err := os.ReadFile("./mock/node.json")
+		if err != nil {
+			t.Fatalf("errore nel leggere il file: %v", err)
+		}
+
+		var payload struct {
+			Data goplatform.Node `json:"data"`
+		}
+		if err := json.Unmarshal(b, &payload); err != nil {
+			t.Fatal(err)
+		}
+		node := payload.Data
+
+		if node.Connected() || node.ConnectivityStatus != goplatform.CONNECTIVITY_STATUS_DISCONNECTED {
+			t.Fatalf("expected disconnected node got %s", node.ConnectivityStatus)
+		}
+
+		connected, ok := node.LastConnection()
+		if !ok || !connected.Equal(time.Date(2025, 2, 7, 11, 9, 35, 792000000, time.UTC)) {
+			t.Fatalf("expected last connection 2025-02-07T11:09:35.792Z got %v", connected)
+		}
+
+		disconnected, _ := node.LastDisconnection()
+		offline, ok := node.OfflineFor(disconnected.Add(time.Hour))
+		if !ok || offline != time.Hour {
+			t.Fatalf("expected offline for 1h got %v", offline)
+		}
+	})
+
+	t.Run("Device", func(t *testing.T) {
+		b, err := os.ReadFile("./mock/devices.json")
+		if err != nil {
+			t.Fatalf("errore nel leggere il file: %v", err)
+		}
+
+		var payload struct {
+			Data []goplatform.Device `json:"data"`
+		}
+		if err := json.Unmarshal(b, &payload); err != nil {
+			t.Fatal(err)
+		}
+
+		never := payload.Data[0]
+		if _, ok := never.LastConnection(); ok {
+			t.Fatal("expected no last connection")
+		}
+		if _, ok := never.OfflineFor(time.Now()); ok {
+			t.Fatal("expected unknown offline time")
+		}
+
+		device := payload.Data[1]
+		communicated, ok := device.LastCommunication()
+		if !ok || communicated.Year() != 2024 {
+			t.Fatalf("expected 2024 last communication got %v", communicated)
+		}
+
+		offline, ok := device.OfflineFor(communicated.Add(30 * time.Minute))
+		if !ok || offline != 30*time.Minute {
+			t.Fatalf("expected offline for 30m got %v", offline)
+		}
+
+		device.ConnectivityStatus = goplatform.CONNECTIVITY_STATUS_CONNECTED
+		if _, ok := device.OfflineFor(time.Now()); ok {
+			t.Fatal("expected connected device not offline")
+		}
+	})
+}
@@ -0,0 +1,97 @@
+package goplatform_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ApioIoT/goplatform/v2"
+)
+
+func TestCrontab(t *testing.T) {
+	from := time.Date(2025, 1, 31, 23, 58, 30, 0, time.UTC)
+
+	t.Run("NextFireTimes", func(t *testing.T) {
+		cases := []struct {
+			expr     string
+			expected []time.Time
+		}{
+			{"*/5 * * * *", []time.Time{
+				time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC),
+				time.Date(2025, 2, 1, 0, 5, 0, 0, time.UTC),
+			}},
+			{"30 8 * * MON-FRI", []time.Time{
+				time.Date(2025, 2, 3, 8, 30, 0, 0, time.UTC),
+				time.Date(2025, 2, 4, 8, 30, 0, 0, time.UTC),
+			}},
+			{"0 0 29 2 *", []time.Time{
+				time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC),
+			}},
+			{"15,45 * * * * *", []time.Time{
+				time.Date(2025, 1, 31, 23, 58, 45, 0, time.UTC),
+				time.Date(2025, 1, 31, 23, 59, 15, 0, time.UTC),
+			}},
+			{"@monthly", []time.Time{
+				time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC),
+				time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+			}},
+			{"0 12 1 * 7", []time.Time{
+				time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC),
+				time.Date(2025, 2, 2, 12, 0, 0, 0, time.UTC),
+			}},
+		}
+
+		for _, c := range cases {
+			crontab, err := goplatform.ParseCrontab(c.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			times := crontab.NextFireTimes(len(c.expected), from, time.UTC)
+			if len(times) != len(c.expected) {
+				t.Fatalf("%s: expected %d times, got %d", c.expr, len(c.expected), len(times))
+			}
+			for i := range times {
+				if !times[i].Equal(c.expected[i]) {
+					t.Fatalf("%s: expected %s, got %s", c.expr, c.expected[i], times[i])
+				}
+			}
+		}
+	})
+
+	t.Run("Location", func(t *testing.T) {
+		rome, err := time.LoadLocation("Europe/Rome")
+		if err != nil {
+			t.Skip(err)
+		}
+
+		times, err := goplatform.CronTrigger("0 9 * * *").NextFireTimes(1, from, rome)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !times[0].Equal(time.Date(2025, 2, 1, 8, 0, 0, 0, time.UTC)) {
+			t.Fatalf("expected 08:00 UTC, got %s", times[0].UTC())
+		}
+	})
+
+	t.Run("Never", func(t *testing.T) {
+		crontab, err := goplatform.ParseCrontab("0 0 30 2 *")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if times := crontab.NextFireTimes(1, from, nil); len(times) != 0 {
+			t.Fatalf("expected no fire times, got %v", times)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "*/0 * * * *", "5-1 * * * *", "* * * FOO *"} {
+			if _, err := goplatform.ParseCrontab(expr); err == nil {
+				t.Fatalf("expected error for %q", expr)
+			}
+		}
+
+		if err := goplatform.CronTrigger("61 * * * *").Validate(); err == nil {
+			t.Fatal("expected error validating invalid crontab trigger")
+		}
+	})
+}
@@ -0,0 +1,141 @@
+package goplatform_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/ApioIoT/goplatform/v2"
+	"github.com/h2non/gock"
+)
+
+func TestSendCommand(t *testing.T) {
+	defer gock.Off()
+
+	gock.New(API_URI).
+		Get("/projects/" + PROJECT_ID + "/devices/" + DEVICE_ID).
+		Persist().
+		Reply(200).
+		JSON(map[string]any{"status": true, "data": map[string]any{
+			"uuid":         DEVICE_ID,
+			"projectId":    PROJECT_ID,
+			"nodeId":       NODE_ID,
+			"deviceTypeId": DEVICE_TYPE_ID,
+			"name":         "relay",
+			"deviceType": map[string]any{
+				"uuid": DEVICE_TYPE_ID,
+				"name": "relay",
+				"commands": map[string]any{
+					"setOutput": map[string]any{
+						"parameters": map[string]any{
+							"output": map[string]any{"type": "integer", "required": true},
+							"value":  map[string]any{"type": "boolean", "required": true},
+						},
+					},
+				},
+			},
+		}})
+
+	gock.New(API_URI).
+		Get("/projects/" + PROJECT_ID + "/nodes/" + NODE_ID).
+		Persist().
+		Reply(200).
+		JSON(map[string]any{"status": true, "data": map[string]any{"uuid": NODE_ID, "projectId": PROJECT_ID, "name": "gateway"}})
+
+	var sent []goplatform.Command
+	gock.New(API_URI).
+		Post("/projects/" + PROJECT_ID + "/commands").
+		Persist().
+		AddMatcher(func(req *http.Request, ereq *gock.Request) (bool, error) {
+			b, err := io.ReadAll(req.Body)
+			if err != nil {
+				return false, err
+			}
+			var command goplatform.Command
+			if err := json.Unmarshal(b, &command); err != nil {
+				return false, err
+			}
+			sent = append(sent, command)
+			return true, nil
+		}).
+		Reply(200)
+
+	platform := goplatform.New(goplatform.Config{
+		Uri:    API_URI,
+		ApiKey: API_KEY,
+	})
+	project := platform.Project(PROJECT_ID)
+	ctx := context.Background()
+
+	device, err := project.GetDevice(ctx, DEVICE_ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Device", func(t *testing.T) {
+		command, err := device.SendCommand(ctx, "setOutput", goplatform.CommandParameter{"output": 1, "value": true}, goplatform.SendCommandOptions{
+			Metadata: map[string]any{"source": "test"},
+			Validate: true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if command.Uuid == "" || command.Status != goplatform.CMD_STATUS_PENDING {
+			t.Fatalf("expected pending command got %+v", command)
+		}
+		if command.ProjectId != PROJECT_ID || *command.DeviceId != DEVICE_ID || *command.NodeId != NODE_ID {
+			t.Fatalf("expected ids to be filled in got %+v", command)
+		}
+
+		last := sent[len(sent)-1]
+		if last.Uuid != command.Uuid || last.Parameters[0]["output"] != 1.0 || last.Metadata["source"] != "test" {
+			t.Fatalf("expected sent command %s got %+v", command.Uuid, last)
+		}
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		count := len(sent)
+
+		cases := []struct {
+			name   string
+			params goplatform.CommandParameter
+		}{
+			{"reboot", nil},
+			{"setOutput", goplatform.CommandParameter{"output": 1}},
+			{"setOutput", goplatform.CommandParameter{"output": 1.5, "value": true}},
+			{"setOutput", goplatform.CommandParameter{"output": 1, "value": true, "delay": 10}},
+		}
+
+		for _, c := range cases {
+			if _, err := device.SendCommand(ctx, c.name, c.params, goplatform.SendCommandOptions{Validate: true}); err == nil {
+				t.Fatalf("expected validation error for %s %v", c.name, c.params)
+			}
+		}
+
+		if len(sent) != count {
+			t.Fatal("expected invalid commands not to be sent")
+		}
+
+		if _, err := device.SendCommand(ctx, "reboot", nil); err != nil {
+			t.Fatalf("expected unvalidated command to be sent got %v", err)
+		}
+	})
+
+	t.Run("Node", func(t *testing.T) {
+		node, err := project.GetNode(ctx, NODE_ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		command, err := node.SendCommand(ctx, "reboot", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *command.NodeId != NODE_ID || command.DeviceId != nil {
+			t.Fatalf("expected node command got %+v", command)
+		}
+	})
+}
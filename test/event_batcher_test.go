@@ -0,0 +1,213 @@
+package goplatform_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ApioIoT/goplatform/v2"
+	"github.com/h2non/gock"
+)
+
+func TestEventBatcher(t *testing.T) {
+	defer gock.Off()
+
+	gock.New(API_URI).
+		Get("/projects/" + PROJECT_ID + "/").
+		Persist().
+		Reply(200).
+		JSON(map[string]any{"status": true, "data": map[string]any{"uuid": PROJECT_ID}})
+
+	platform := goplatform.New(goplatform.Config{
+		Uri:    API_URI,
+		ApiKey: API_KEY,
+	})
+
+	ctx := context.Background()
+
+	project, err := platform.GetProject(ctx, PROJECT_ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Bulk", func(t *testing.T) {
+		defer gock.Flush()
+
+		var mu sync.Mutex
+		var batches []int
+		gock.New(API_URI).
+			Post("/projects/" + PROJECT_ID + "/events/bulk").
+			Persist().
+			AddMatcher(func(req *http.Request, ereq *gock.Request) (bool, error) {
+				b, err := io.ReadAll(req.Body)
+				if err != nil {
+					return false, err
+				}
+				var events []goplatform.Event
+				if err := json.Unmarshal(b, &events); err != nil {
+					return false, err
+				}
+				for _, event := range events {
+					if event.ProjectID != PROJECT_ID {
+						return false, nil
+					}
+				}
+				mu.Lock()
+				batches = append(batches, len(events))
+				mu.Unlock()
+				return true, nil
+			}).
+			Reply(200)
+
+		batcher := goplatform.NewEventBatcher(project, goplatform.EventBatcherOptions{
+			MaxBatchSize:  10,
+			FlushInterval: time.Hour,
+		})
+
+		var wg sync.WaitGroup
+		for i := range 25 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := batcher.Add(goplatform.Event{Type: "Notification", Source: fmt.Sprintf("test/%d", i)}); err != nil {
+					t.Error(err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		if err := batcher.Close(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		total := 0
+		for _, n := range batches {
+			if n > 10 {
+				t.Fatalf("expected batches of at most 10 events got %d", n)
+			}
+			total += n
+		}
+		if total != 25 {
+			t.Fatalf("expected 25 events got %d", total)
+		}
+
+		if err := batcher.Add(goplatform.Event{Type: "Notification"}); err == nil {
+			t.Fatal("expected error adding to a closed batcher")
+		}
+	})
+
+	t.Run("Fallback", func(t *testing.T) {
+		defer gock.Flush()
+
+		gock.New(API_URI).
+			Post("/projects/" + PROJECT_ID + "/events/bulk").
+			Reply(http.StatusNotFound)
+
+		gock.New(API_URI).
+			Post("/projects/" + PROJECT_ID + "/events").
+			BodyString(`"source":"test/bad"`).
+			Persist().
+			Reply(http.StatusBadRequest).
+			JSON(map[string]any{"status": false, "error": map[string]any{"name": "ValidationError", "message": "invalid event"}})
+
+		gock.New(API_URI).
+			Post("/projects/" + PROJECT_ID + "/events").
+			Persist().
+			Reply(200)
+
+		var failures []goplatform.EventFailure
+		batcher := goplatform.NewEventBatcher(project, goplatform.EventBatcherOptions{
+			FlushInterval: time.Hour,
+			OnFailure: func(f []goplatform.EventFailure) {
+				failures = append(failures, f...)
+			},
+		})
+
+		for _, source := range []string{"test/ok", "test/bad", "test/ok"} {
+			if err := batcher.Add(goplatform.Event{Type: "Notification", Source: source}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := batcher.Close(ctx); err == nil {
+			t.Fatal("expected error for the invalid event")
+		}
+
+		if len(failures) != 1 || failures[0].Event.Source != "test/bad" {
+			t.Fatalf("expected test/bad failure got %+v", failures)
+		}
+	})
+	t.Run("Close deadline", func(t *testing.T) {
+		defer gock.Flush()
+
+		gock.New(API_URI).
+			Post("/projects/" + PROJECT_ID + "/events/bulk").
+			Persist().
+			Reply(200).
+			Delay(10 * time.Second)
+
+		sending := make(chan struct{}, 1)
+		gock.Observe(func(req *http.Request, mock gock.Mock) {
+			sending <- struct{}{}
+		})
+		defer gock.Observe(nil)
+
+		batcher := goplatform.NewEventBatcher(project, goplatform.EventBatcherOptions{MaxBatchSize: 1})
+		if err := batcher.Add(goplatform.Event{Type: "Notification", Source: "test/slow"}); err != nil {
+			t.Fatal(err)
+		}
+		<-sending
+
+		ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		if err := batcher.Close(ctx); err == nil {
+			t.Fatal("expected error for the canceled flush")
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Fatalf("expected Close to honour the deadline, took %s", elapsed)
+		}
+	})
+
+	t.Run("Background failure", func(t *testing.T) {
+		defer gock.Flush()
+
+		gock.New(API_URI).
+			Post("/projects/" + PROJECT_ID + "/events/bulk").
+			Persist().
+			Reply(http.StatusInternalServerError)
+
+		failed := make(chan struct{}, 1)
+		batcher := goplatform.NewEventBatcher(project, goplatform.EventBatcherOptions{
+			MaxBatchSize: 1,
+			OnFailure: func(f []goplatform.EventFailure) {
+				failed <- struct{}{}
+			},
+		})
+		defer batcher.Close(ctx) //nolint:errcheck
+
+		if err := batcher.Add(goplatform.Event{Type: "Notification", Source: "test/event"}); err != nil {
+			t.Fatal(err)
+		}
+		<-failed
+
+		// The failure is recorded right after OnFailure returns
+		err := batcher.Flush(ctx)
+		for deadline := time.Now().Add(time.Second); err == nil && time.Now().Before(deadline); {
+			time.Sleep(5 * time.Millisecond)
+			err = batcher.Flush(ctx)
+		}
+		if err == nil {
+			t.Fatal("expected the background failure to be returned")
+		}
+		if err := batcher.Flush(ctx); err != nil {
+			t.Fatalf("expected the failure to be returned once got %v", err)
+		}
+	})
+}
@@ -0,0 +1,135 @@
+package goplatform_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ApioIoT/goplatform/v2"
+	"github.com/h2non/gock"
+)
+
+func TestEvents(t *testing.T) {
+	defer gock.Off()
+
+	eventsUrl := "/projects/" + PROJECT_ID + "/events"
+
+	gock.New(API_URI).
+		Get("/projects/" + PROJECT_ID + "/").
+		Reply(200).
+		JSON(map[string]any{"status": true, "data": map[string]any{"uuid": PROJECT_ID}})
+
+	gock.New(API_URI).
+		Get(eventsUrl).
+		MatchParams(map[string]string{
+			"type":          "Alarm",
+			"source":        "test/event",
+			"from":          "2025-01-01T00:00:00Z",
+			"to":            "2025-01-02T00:00:00Z",
+			"metadata.site": "milano",
+			"limit":         "2",
+			"offset":        "0",
+		}).
+		Reply(200).
+		JSON(map[string]any{
+			"status": true,
+			"data": []map[string]any{
+				{"uuid": "e1", "type": "Alarm", "source": "test/event", "eventTime": "2025-01-01T10:00:00Z"},
+				{"uuid": "e2", "type": "Alarm", "source": "test/event", "eventTime": "2025-01-01T11:00:00Z"},
+			},
+			"meta": map[string]any{"total": 3},
+		})
+
+	gock.New(API_URI).
+		Get(eventsUrl).
+		MatchParam("offset", "2").
+		Reply(200).
+		JSON(map[string]any{
+			"status": true,
+			"data": []map[string]any{
+				{"uuid": "e3", "type": "Alarm", "source": "test/event", "eventTime": "2025-01-01T12:00:00Z"},
+			},
+			"meta": map[string]any{"total": 3},
+		})
+
+	gock.New(API_URI).
+		Get(eventsUrl + "/e2").
+		Reply(200).
+		JSON(map[string]any{
+			"status": true,
+			"data":   map[string]any{"uuid": "e2", "type": "Alarm", "source": "test/event", "data": map[string]any{"level": 2}},
+		})
+
+	platform := goplatform.New(goplatform.Config{
+		Uri:    API_URI,
+		ApiKey: API_KEY,
+	})
+
+	ctx := context.Background()
+
+	project, err := platform.GetProject(ctx, PROJECT_ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("GetEvents", func(t *testing.T) {
+		query := goplatform.EventQuery{
+			Type:     "Alarm",
+			Source:   "test/event",
+			From:     time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+			To:       time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC),
+			Metadata: map[string]string{"site": "milano"},
+			Limit:    2,
+		}
+
+		var uuids []string
+		for {
+			page, err := project.GetEvents(ctx, query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if page.Total != 3 {
+				t.Fatalf("expected total 3 got %d", page.Total)
+			}
+			for _, event := range page.Events {
+				uuids = append(uuids, event.Uuid)
+			}
+
+			next, ok := page.Next()
+			if !ok {
+				break
+			}
+			query = next
+		}
+
+		if len(uuids) != 3 || uuids[0] != "e1" || uuids[2] != "e3" {
+			t.Fatalf("expected events e1, e2, e3 got %v", uuids)
+		}
+	})
+
+	t.Run("Empty page", func(t *testing.T) {
+		gock.New(API_URI).
+			Get(eventsUrl).
+			MatchParam("offset", "5").
+			Reply(200).
+			JSON(map[string]any{"status": true, "data": []map[string]any{}, "meta": map[string]any{"total": 10}})
+
+		page, err := project.GetEvents(ctx, goplatform.EventQuery{Offset: 5})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := page.Next(); ok {
+			t.Fatal("expected an empty page to be the last one")
+		}
+	})
+
+	t.Run("GetEvent", func(t *testing.T) {
+		event, err := project.GetEvent(ctx, "e2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if event.Uuid != "e2" || event.Type != "Alarm" {
+			t.Fatalf("expected event e2 got %+v", event)
+		}
+	})
+}
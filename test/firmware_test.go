@@ -0,0 +1,358 @@
+package goplatform_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ApioIoT/goplatform/v2"
+	"github.com/h2non/gock"
+)
+
+// firmwareServer simulates devices installing the firmware version of the
+// update commands they receive, except the failing ones.
+type firmwareServer struct {
+	mu       sync.Mutex
+	versions map[string]string
+	failing  map[string]bool
+	commands []string
+	// statuses are the statuses of the commands, by uuid
+	statuses map[string]goplatform.CommandStatus
+	// unavailable is the number of requests for a device answered with 503
+	unavailable map[string]int
+}
+
+func (s *firmwareServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prefix := "/projects/" + PROJECT_ID + "/"
+	path := strings.TrimPrefix(req.URL.Path, prefix)
+
+	switch {
+	case req.Method == http.MethodGet && strings.HasPrefix(path, "devices/"):
+		id := strings.TrimPrefix(path, "devices/")
+		if s.unavailable[id] > 0 {
+			s.unavailable[id]--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		version, ok := s.versions[id]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"status": true, "data": map[string]any{ //nolint:errcheck
+			"uuid":            id,
+			"projectId":       PROJECT_ID,
+			"deviceTypeId":    DEVICE_TYPE_ID,
+			"firmwareVersion": version,
+			"deviceType": map[string]any{
+				"uuid":             DEVICE_TYPE_ID,
+				"name":             "sensor",
+				"firmwareId":       "my-firmware-id",
+				"firmwareVersions": []string{"1.0", "2.0"},
+			},
+		}})
+	case req.Method == http.MethodPost && path == "commands":
+		var command goplatform.Command
+		if err := json.NewDecoder(req.Body).Decode(&command); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.commands = append(s.commands, *command.DeviceId)
+		s.statuses[command.Uuid] = goplatform.CMD_STATUS_FAILED
+		if command.Name == goplatform.FIRMWARE_UPDATE_COMMAND && !s.failing[*command.DeviceId] {
+			s.versions[*command.DeviceId] = command.Parameters[0]["version"].(string)
+			s.statuses[command.Uuid] = goplatform.CMD_STATUS_COMPLETED
+		}
+		w.WriteHeader(http.StatusOK)
+	case req.Method == http.MethodGet && strings.HasPrefix(path, "commands/"):
+		id := strings.TrimPrefix(path, "commands/")
+		status, ok := s.statuses[id]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"status": true, "data": map[string]any{"uuid": id, "status": status}}) //nolint:errcheck
+	case req.Method == http.MethodPost && path == "firmwares":
+		file, _, err := req.FormFile("file")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		image, _ := io.ReadAll(file)
+		var firmware goplatform.Firmware
+		json.Unmarshal([]byte(req.FormValue("metadata")), &firmware) //nolint:errcheck
+		firmware.Uuid = "my-firmware-id"
+		if int64(len(image)) != firmware.Size {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"status": true, "data": firmware}) //nolint:errcheck
+	case req.Method == http.MethodGet && path == "firmwares":
+		json.NewEncoder(w).Encode(map[string]any{"status": true, "data": []map[string]any{{"uuid": "my-firmware-id", "version": "2.0"}}}) //nolint:errcheck
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestFirmware(t *testing.T) {
+	// Devices change firmware version while the campaign runs, so they are
+	// served by a real server rather than by static mocks
+	gock.Off()
+
+	server := &firmwareServer{
+		versions:    map[string]string{"d1": "1.0", "d2": "1.0", "d3": "1.0", "d4": "1.0", "d5": "1.0"},
+		failing:     map[string]bool{},
+		statuses:    map[string]goplatform.CommandStatus{},
+		unavailable: map[string]int{},
+	}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	platform := goplatform.New(goplatform.Config{
+		Uri:    ts.URL,
+		ApiKey: API_KEY,
+		// Gives the client a transport to reach the server past gock
+		SkipVerify: true,
+	})
+	project := platform.Project(PROJECT_ID)
+	ctx := context.Background()
+
+	spec := goplatform.FirmwareCampaignSpec{
+		Version:          "2.0",
+		DeviceIds:        []string{"d1", "d2", "d3", "d4", "d5"},
+		CanaryPercent:    20,
+		WaveSize:         2,
+		MaxConcurrent:    2,
+		FailureThreshold: 0.3,
+		Timeout:          50 * time.Millisecond,
+		PollInterval:     5 * time.Millisecond,
+	}
+
+	t.Run("Upload", func(t *testing.T) {
+		firmware, err := project.UploadFirmware(ctx, goplatform.Firmware{Name: "sensor", Version: "2.0"}, strings.NewReader("firmware image"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if firmware.Uuid != "my-firmware-id" || firmware.Size != 14 || len(firmware.Checksum) != 64 {
+			t.Fatalf("unexpected firmware %+v", firmware)
+		}
+
+		firmwares, err := project.GetFirmwares(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(firmwares) != 1 || firmwares[0].Version != "2.0" {
+			t.Fatalf("expected firmware 2.0 got %+v", firmwares)
+		}
+	})
+
+	t.Run("UpdateFirmware", func(t *testing.T) {
+		device, err := project.GetDevice(ctx, "d1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := device.UpdateFirmware(ctx, "3.0"); err == nil {
+			t.Fatal("expected error for an unknown firmware version")
+		}
+	})
+
+	t.Run("Campaign", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "campaign.json")
+
+		campaign, err := goplatform.NewFirmwareCampaign(project, path, spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := campaign.Run(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		status, devices := campaign.Status()
+		if status != goplatform.CAMPAIGN_COMPLETED {
+			t.Fatalf("expected completed campaign got %s", status)
+		}
+		waves := []int{0, 1, 1, 2, 2}
+		for i, d := range devices {
+			if d.Status != goplatform.CAMPAIGN_DEVICE_SUCCEEDED || d.Wave != waves[i] {
+				t.Fatalf("expected %s succeeded in wave %d got %+v", d.DeviceId, waves[i], d)
+			}
+		}
+	})
+
+	t.Run("Transient errors", func(t *testing.T) {
+		server.mu.Lock()
+		for id := range server.versions {
+			server.versions[id] = "1.0"
+		}
+		server.unavailable["d3"] = 2
+		server.mu.Unlock()
+
+		campaign, err := goplatform.NewFirmwareCampaign(project, filepath.Join(t.TempDir(), "campaign.json"), spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := campaign.Run(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, devices := campaign.Status(); devices[2].Status != goplatform.CAMPAIGN_DEVICE_SUCCEEDED {
+			t.Fatalf("expected d3 to succeed after retrying got %+v", devices[2])
+		}
+	})
+
+	t.Run("Spec mismatch", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "campaign.json")
+		if _, err := goplatform.NewFirmwareCampaign(project, path, spec); err != nil {
+			t.Fatal(err)
+		}
+
+		other := spec
+		other.Version = "3.0"
+		if _, err := goplatform.NewFirmwareCampaign(project, path, other); err == nil {
+			t.Fatal("expected error resuming with a different spec")
+		}
+		if _, err := goplatform.NewFirmwareCampaign(project, path, goplatform.FirmwareCampaignSpec{}); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Save failure", func(t *testing.T) {
+		server.mu.Lock()
+		for id := range server.versions {
+			server.versions[id] = "1.0"
+		}
+		server.mu.Unlock()
+
+		dir := filepath.Join(t.TempDir(), "campaign")
+		if err := os.Mkdir(dir, 0o700); err != nil {
+			t.Fatal(err)
+		}
+		campaign, err := goplatform.NewFirmwareCampaign(project, filepath.Join(dir, "campaign.json"), spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := campaign.Run(ctx); err == nil {
+			t.Fatal("expected error persisting the campaign")
+		}
+	})
+
+	t.Run("Halt", func(t *testing.T) {
+		server.mu.Lock()
+		for id := range server.versions {
+			server.versions[id] = "1.0"
+		}
+		server.failing["d2"] = true
+		server.commands = nil
+		server.mu.Unlock()
+
+		path := filepath.Join(t.TempDir(), "campaign.json")
+
+		campaign, err := goplatform.NewFirmwareCampaign(project, path, spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := campaign.Run(ctx); !errors.Is(err, goplatform.ErrCampaignHalted) {
+			t.Fatalf("expected halted campaign got %v", err)
+		}
+
+		_, devices := campaign.Status()
+		if devices[1].Status != goplatform.CAMPAIGN_DEVICE_FAILED || devices[3].Status != goplatform.CAMPAIGN_DEVICE_PENDING {
+			t.Fatalf("expected d2 failed and d4 pending got %+v", devices)
+		}
+
+		resumed, err := goplatform.NewFirmwareCampaign(project, path, spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := resumed.Run(ctx); !errors.Is(err, goplatform.ErrCampaignHalted) {
+			t.Fatalf("expected resumed campaign to stay halted got %v", err)
+		}
+	})
+
+	t.Run("Failed command", func(t *testing.T) {
+		server.mu.Lock()
+		server.versions["d2"] = "1.0"
+		server.failing["d2"] = true
+		server.mu.Unlock()
+
+		slow := spec
+		slow.DeviceIds = []string{"d2"}
+		slow.Timeout = 10 * time.Second
+
+		campaign, err := goplatform.NewFirmwareCampaign(project, filepath.Join(t.TempDir(), "campaign.json"), slow)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		start := time.Now()
+		if err := campaign.Run(ctx); !errors.Is(err, goplatform.ErrCampaignHalted) {
+			t.Fatalf("expected halted campaign got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Fatalf("expected the failed command to fail the device before the timeout, took %v", elapsed)
+		}
+
+		_, devices := campaign.Status()
+		if devices[0].Status != goplatform.CAMPAIGN_DEVICE_FAILED || !strings.Contains(devices[0].Error, "failed") {
+			t.Fatalf("expected d2 failed by its command got %+v", devices[0])
+		}
+	})
+
+	t.Run("Resume", func(t *testing.T) {
+		server.mu.Lock()
+		for id := range server.versions {
+			server.versions[id] = "1.0"
+		}
+		server.versions["d1"] = "2.0"
+		server.failing = map[string]bool{}
+		server.commands = nil
+		server.mu.Unlock()
+
+		// A campaign interrupted after sending the update to d1
+		now := time.Now()
+		state := map[string]any{
+			"spec":   spec,
+			"status": goplatform.CAMPAIGN_RUNNING,
+			"devices": []goplatform.CampaignDevice{
+				{DeviceId: "d1", Wave: 0, Status: goplatform.CAMPAIGN_DEVICE_UPDATING, StartedAt: &now},
+				{DeviceId: "d2", Wave: 1, Status: goplatform.CAMPAIGN_DEVICE_PENDING},
+			},
+		}
+		b, err := json.Marshal(state)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(t.TempDir(), "campaign.json")
+		if err := os.WriteFile(path, b, 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		campaign, err := goplatform.NewFirmwareCampaign(project, path, goplatform.FirmwareCampaignSpec{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := campaign.Run(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(server.commands) != 1 || server.commands[0] != "d2" {
+			t.Fatalf("expected only d2 to receive a command got %v", server.commands)
+		}
+	})
+}
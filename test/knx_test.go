@@ -0,0 +1,143 @@
+package goplatform_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ApioIoT/goplatform/v2"
+	"github.com/ApioIoT/goplatform/v2/knx"
+)
+
+func TestKnx(t *testing.T) {
+	t.Run("GroupAddress", func(t *testing.T) {
+		cases := map[string]knx.GroupAddress{
+			"1/2/3": 0x0A03,
+			"1/515": 0x0A03,
+			"2563":  0x0A03,
+		}
+
+		for s, expected := range cases {
+			address, err := knx.ParseGroupAddress(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if address != expected {
+				t.Fatalf("expected %s to be %d, got %d", s, expected, address)
+			}
+		}
+
+		address := knx.GroupAddress(0x0A03)
+		if address.String() != "1/2/3" || address.TwoLevel() != "1/515" || address.Free() != "2563" {
+			t.Fatalf("unexpected formats %s %s %s", address.String(), address.TwoLevel(), address.Free())
+		}
+	})
+
+	t.Run("GroupAddress Invalid", func(t *testing.T) {
+		for _, s := range []string{"", "0/0/0", "32/0/0", "1/8/0", "1/2/256", "1/2048", "65536", "a/b/c", "1/2/3/4"} {
+			if _, err := knx.ParseGroupAddress(s); err == nil {
+				t.Fatalf("expected error for %q", s)
+			}
+		}
+	})
+
+	t.Run("ParseDPT", func(t *testing.T) {
+		for _, s := range []string{"9.001", "DPT9.001", "DPST-9-1", "dpt-9"} {
+			dpt, err := knx.ParseDPT(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if dpt.Main != 9 {
+				t.Fatalf("expected main type 9 for %q, got %d", s, dpt.Main)
+			}
+		}
+
+		if _, err := knx.ParseDPT("232.600"); err == nil {
+			t.Fatal("expected error for unsupported datapoint type")
+		}
+	})
+
+	t.Run("Encode", func(t *testing.T) {
+		cases := []struct {
+			dpt      string
+			value    any
+			expected []byte
+			decoded  any
+		}{
+			{"1.001", true, []byte{0x01}, true},
+			{"5.001", 100, []byte{0xFF}, float64(100)},
+			{"5.010", 42, []byte{0x2A}, uint8(42)},
+			{"7.001", 1000, []byte{0x03, 0xE8}, uint16(1000)},
+			{"8.001", -1, []byte{0xFF, 0xFF}, int16(-1)},
+			{"9.001", 21.5, []byte{0x0C, 0x33}, 21.5},
+			{"9.001", -30, []byte{0x8A, 0x24}, float64(-30)},
+			{"14.056", 1.5, []byte{0x3F, 0xC0, 0x00, 0x00}, 1.5},
+			{"16.000", "KNX", []byte{'K', 'N', 'X', 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}, "KNX"},
+		}
+
+		for _, c := range cases {
+			b, err := knx.Encode(c.dpt, c.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(b, c.expected) {
+				t.Fatalf("%s: expected % X, got % X", c.dpt, c.expected, b)
+			}
+
+			value, err := knx.Decode(c.dpt, b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if value != c.decoded {
+				t.Fatalf("%s: expected %v (%T), got %v (%T)", c.dpt, c.decoded, c.decoded, value, value)
+			}
+		}
+	})
+
+	t.Run("Encode Out of Range", func(t *testing.T) {
+		cases := []struct {
+			dpt   string
+			value any
+		}{
+			{"5.010", 256},
+			{"7.001", -1},
+			{"8.001", 40000},
+			{"9.001", 700000},
+			{"16.000", "à"},
+			{"16.001", "this string is too long"},
+		}
+
+		for _, c := range cases {
+			if _, err := knx.Encode(c.dpt, c.value); err == nil {
+				t.Fatalf("%s: expected error for %v", c.dpt, c.value)
+			}
+		}
+	})
+
+	t.Run("DeviceTypeKnxProtocol", func(t *testing.T) {
+		protocol := goplatform.DeviceTypeKnxProtocol{
+			Properties: map[string]goplatform.DeviceTypeKnxProperty{
+				"temperature": {Address: "1/2/3", SendDPT: "9.001", ReceiveDPT: "9.001"},
+			},
+		}
+
+		if err := protocol.Validate(); err != nil {
+			t.Fatal(err)
+		}
+
+		address, data, err := protocol.EncodeProperty("temperature", 21.5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if address.String() != "1/2/3" {
+			t.Fatalf("expected address to be '1/2/3', got '%s'", address)
+		}
+
+		value, err := protocol.DecodeProperty("temperature", data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value != 21.5 {
+			t.Fatalf("expected value to be 21.5, got %v", value)
+		}
+	})
+}
@@ -0,0 +1,236 @@
+package goplatform_test
+
+import (
+	"encoding/json"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/ApioIoT/goplatform/v2"
+)
+
+func loadModbusProtocol(t *testing.T) goplatform.DeviceTypeModbusProtocol {
+	t.Helper()
+
+	b, err := os.ReadFile("./mock/devicetype.json")
+	if err != nil {
+		t.Fatalf("errore nel leggere il file: %v", err)
+	}
+
+	var payload struct {
+		Data goplatform.DeviceType `json:"data"`
+	}
+	if err := json.Unmarshal(b, &payload); err != nil {
+		t.Fatal(err)
+	}
+
+	return *payload.Data.Protocols.Modbus
+}
+
+func TestModbus(t *testing.T) {
+	t.Run("EncodeRegisterWrite", func(t *testing.T) {
+		protocol := loadModbusProtocol(t)
+
+		req, err := goplatform.EncodeRegisterWrite(protocol, "output1", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if req.Function != goplatform.MODBUS_FC_WRITE_SINGLE_REGISTER {
+			t.Fatalf("expected function to be 6, got %d", req.Function)
+		}
+		if req.Address != 8 {
+			t.Fatalf("expected address to be 8, got %d", req.Address)
+		}
+		if !slices.Equal(req.Words, []uint16{1}) {
+			t.Fatalf("expected words to be [1], got %v", req.Words)
+		}
+	})
+
+	t.Run("EncodeRegisterWrite Unknown Property", func(t *testing.T) {
+		protocol := loadModbusProtocol(t)
+
+		if _, err := goplatform.EncodeRegisterWrite(protocol, "unknown", 1); err == nil {
+			t.Fatal("expected error for unknown property")
+		}
+	})
+
+	t.Run("EncodeRegisterWrite Read-only", func(t *testing.T) {
+		protocol := goplatform.DeviceTypeModbusProtocol{
+			Endianness: goplatform.MODBUS_ENDIANNESS_ABCD,
+			Registers: []goplatform.DeviceTypeModbusRegister{
+				{
+					Register:           1,
+					Read:               true,
+					ModbusFunctionRead: 3,
+					Words:              1,
+					Type:               "uint",
+					Properties:         []goplatform.DeviceTypeModbusProperty{{Index: 0, Name: "temperature"}},
+				},
+			},
+		}
+
+		if _, err := goplatform.EncodeRegisterWrite(protocol, "temperature", 1); err == nil {
+			t.Fatal("expected error writing a read-only register")
+		}
+	})
+
+	t.Run("EncodeRegisterWrite Endianness", func(t *testing.T) {
+		scale := 0.1
+		register := goplatform.DeviceTypeModbusRegister{
+			Register:            100,
+			Write:               true,
+			ModbusFunctionWrite: 16,
+			Words:               2,
+			Type:                "int",
+			ScaleFactor:         &scale,
+			Properties:          []goplatform.DeviceTypeModbusProperty{{Index: 0, Name: "setpoint"}},
+		}
+
+		cases := map[string][]uint16{
+			goplatform.MODBUS_ENDIANNESS_ABCD: {0xFFFE, 0x1DC0},
+			goplatform.MODBUS_ENDIANNESS_CDAB: {0x1DC0, 0xFFFE},
+			goplatform.MODBUS_ENDIANNESS_BADC: {0xFEFF, 0xC01D},
+			goplatform.MODBUS_ENDIANNESS_DCBA: {0xC01D, 0xFEFF},
+		}
+
+		for endianness, expected := range cases {
+			protocol := goplatform.DeviceTypeModbusProtocol{
+				Endianness: endianness,
+				Registers:  []goplatform.DeviceTypeModbusRegister{register},
+			}
+
+			req, err := goplatform.EncodeRegisterWrite(protocol, "setpoint", -12345.6)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(req.Words, expected) {
+				t.Fatalf("%s: expected words to be %04X, got %04X", endianness, expected, req.Words)
+			}
+		}
+	})
+
+	t.Run("EncodeRegisterWrite Coils", func(t *testing.T) {
+		cases := map[int][]uint16{
+			goplatform.MODBUS_FC_WRITE_SINGLE_COIL:    {0xFF00},
+			goplatform.MODBUS_FC_WRITE_MULTIPLE_COILS: {0x0001},
+		}
+
+		for function, expected := range cases {
+			protocol := goplatform.DeviceTypeModbusProtocol{
+				Endianness: goplatform.MODBUS_ENDIANNESS_ABCD,
+				Registers: []goplatform.DeviceTypeModbusRegister{{
+					Register:            3,
+					Write:               true,
+					ModbusFunctionWrite: function,
+					Words:               1,
+					Properties:          []goplatform.DeviceTypeModbusProperty{{Index: 0, Name: "relay"}},
+				}},
+			}
+
+			req, err := goplatform.EncodeRegisterWrite(protocol, "relay", true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if req.Function != function || !slices.Equal(req.Words, expected) {
+				t.Fatalf("function %d: expected words to be %04X, got %+v", function, expected, req)
+			}
+
+			req, err = goplatform.EncodeRegisterWrite(protocol, "relay", false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(req.Words, []uint16{0}) {
+				t.Fatalf("function %d: expected words to be [0], got %v", function, req.Words)
+			}
+		}
+	})
+
+	t.Run("EncodeRegisterWrite Overflow", func(t *testing.T) {
+		protocol := loadModbusProtocol(t)
+
+		if _, err := goplatform.EncodeRegisterWrite(protocol, "output1", 70000); err == nil {
+			t.Fatal("expected error for overflowing value")
+		}
+		if _, err := goplatform.EncodeRegisterWrite(protocol, "output1", -1); err == nil {
+			t.Fatal("expected error for negative unsigned value")
+		}
+	})
+}
+
+func TestModbusPlanner(t *testing.T) {
+	properties := func(name string) []goplatform.DeviceTypeModbusProperty {
+		return []goplatform.DeviceTypeModbusProperty{{Index: 0, Name: name}}
+	}
+
+	protocol := goplatform.DeviceTypeModbusProtocol{
+		Endianness: goplatform.MODBUS_ENDIANNESS_CDAB,
+		Registers: []goplatform.DeviceTypeModbusRegister{
+			{Register: 10, Read: true, ModbusFunctionRead: 3, Words: 2, Type: "int", Properties: properties("a")},
+			{Register: 0, Read: true, ModbusFunctionRead: 3, Words: 1, Type: "uint", Properties: properties("b")},
+			{Register: 1, Read: true, ModbusFunctionRead: 3, Words: 1, Type: "uint", Properties: properties("c")},
+			{Register: 4, Read: true, ModbusFunctionRead: 3, Words: 1, Type: "uint", Properties: properties("d")},
+			{Register: 0, Read: true, ModbusFunctionRead: 4, Words: 2, Type: "float", Properties: properties("e")},
+			{Register: 50, Write: true, ModbusFunctionWrite: 6, Words: 1, Type: "uint", Properties: properties("f")},
+		},
+	}
+
+	t.Run("Contiguous", func(t *testing.T) {
+		plan, err := goplatform.PlanModbusReads(protocol)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(plan) != 4 {
+			t.Fatalf("expected 4 requests, got %d", len(plan))
+		}
+		if plan[0].Address != 0 || plan[0].Quantity != 2 || len(plan[0].Registers) != 2 {
+			t.Fatalf("expected first request to cover registers 0-1, got %+v", plan[0])
+		}
+		if plan[3].Function != 4 {
+			t.Fatalf("expected last request to use function 4, got %d", plan[3].Function)
+		}
+	})
+
+	t.Run("Gap", func(t *testing.T) {
+		plan, err := goplatform.PlanModbusReads(protocol, goplatform.ModbusReadPlanOptions{MaxGap: 5})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(plan) != 2 {
+			t.Fatalf("expected 2 requests, got %d", len(plan))
+		}
+		if plan[0].Address != 0 || plan[0].Quantity != 12 {
+			t.Fatalf("expected first request to cover registers 0-11, got %+v", plan[0])
+		}
+
+		values, err := plan[0].Decode(protocol.Endianness, []uint16{1, 2, 0, 0, 3, 0, 0, 0, 0, 0, 0xFFFF, 0xFFFF})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if values["b"] != uint64(1) || values["c"] != uint64(2) || values["d"] != uint64(3) {
+			t.Fatalf("unexpected values %v", values)
+		}
+		if values["a"] != int64(-1) {
+			t.Fatalf("expected a to be -1, got %v", values["a"])
+		}
+	})
+
+	t.Run("MaxLength", func(t *testing.T) {
+		plan, err := goplatform.PlanModbusReads(protocol, goplatform.ModbusReadPlanOptions{MaxGap: 5, MaxLength: 5})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(plan) != 3 {
+			t.Fatalf("expected 3 requests, got %d", len(plan))
+		}
+	})
+
+	t.Run("Short Response", func(t *testing.T) {
+		plan, err := goplatform.PlanModbusReads(protocol)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := plan[0].Split([]uint16{1}); err == nil {
+			t.Fatal("expected error for short response")
+		}
+	})
+}
@@ -0,0 +1,141 @@
+package goplatform_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ApioIoT/goplatform/v2"
+	"github.com/h2non/gock"
+)
+
+func TestOutbox(t *testing.T) {
+	defer gock.Off()
+
+	gock.New(API_URI).
+		Get("/projects/" + PROJECT_ID + "/").
+		Persist().
+		Reply(200).
+		JSON(map[string]any{"status": true, "data": map[string]any{"uuid": PROJECT_ID}})
+
+	platform := goplatform.New(goplatform.Config{
+		Uri:    API_URI,
+		ApiKey: API_KEY,
+	})
+
+	ctx := context.Background()
+
+	project, err := platform.GetProject(ctx, PROJECT_ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	options := goplatform.OutboxOptions{RetryInterval: time.Hour}
+
+	t.Run("Offline", func(t *testing.T) {
+		outbox, err := goplatform.OpenOutbox(dir, project, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer outbox.Close() //nolint:errcheck
+
+		deviceId := DEVICE_ID
+		command := goplatform.CommandRequest{Name: "reboot", ProjectId: PROJECT_ID, DeviceId: &deviceId}.MakeCommand()
+
+		for _, err := range []error{
+			outbox.CreateEvent(goplatform.Event{Uuid: "e1", Type: "Notification", Source: "test/event"}),
+			outbox.SendCommand(command),
+			outbox.SendCommand(command),
+			outbox.CreateEvent(goplatform.Event{Uuid: "e2", Type: "Notification", Source: "test/bad"}),
+			outbox.CreateEvent(goplatform.Event{Uuid: "e3", Type: "Notification", Source: "test/event"}),
+		} {
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := outbox.Flush(ctx); err == nil {
+			t.Fatal("expected flush to fail while offline")
+		}
+
+		if depth := outbox.Depth(); depth != 4 {
+			t.Fatalf("expected depth 4 got %d", depth)
+		}
+		if age := outbox.OldestAge(time.Now().Add(time.Minute)); age < time.Minute {
+			t.Fatalf("expected oldest age of at least 1m got %v", age)
+		}
+	})
+
+	t.Run("Replay", func(t *testing.T) {
+		var mu sync.Mutex
+		var sent []string
+		record := func(req *http.Request, ereq *gock.Request) (bool, error) {
+			b, err := io.ReadAll(req.Body)
+			if err != nil {
+				return false, err
+			}
+			var body struct {
+				Uuid string `json:"uuid"`
+			}
+			if err := json.Unmarshal(b, &body); err != nil {
+				return false, err
+			}
+			if strings.Contains(string(b), "test/bad") {
+				return false, nil
+			}
+			mu.Lock()
+			sent = append(sent, body.Uuid)
+			mu.Unlock()
+			return true, nil
+		}
+
+		gock.New(API_URI).
+			Post("/projects/" + PROJECT_ID + "/events").
+			AddMatcher(record).
+			Persist().
+			Reply(200)
+
+		gock.New(API_URI).
+			Post("/projects/" + PROJECT_ID + "/commands").
+			AddMatcher(record).
+			Persist().
+			Reply(200)
+
+		gock.New(API_URI).
+			Post("/projects/" + PROJECT_ID + "/events").
+			Persist().
+			Reply(http.StatusBadRequest)
+
+		var errs []error
+		outbox, err := goplatform.OpenOutbox(dir, project, goplatform.OutboxOptions{
+			RetryInterval: time.Hour,
+			OnError:       func(err error) { errs = append(errs, err) },
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := outbox.Flush(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if err := outbox.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		if depth := outbox.Depth(); depth != 0 {
+			t.Fatalf("expected empty outbox got %d", depth)
+		}
+		if len(sent) != 3 || sent[0] != "e1" || sent[2] != "e3" {
+			t.Fatalf("expected e1, command, e3 got %v", sent)
+		}
+		if len(errs) != 1 || !strings.Contains(errs[0].Error(), "e2") {
+			t.Fatalf("expected e2 to be dropped got %v", errs)
+		}
+	})
+}
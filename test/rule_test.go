@@ -0,0 +1,409 @@
+package goplatform_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ApioIoT/goplatform/v2"
+)
+
+func TestRuleCondition(t *testing.T) {
+	condition := goplatform.RuleCondition{
+		Devices: map[string]string{
+			"device": "my-device-id",
+			"meter":  "my-meter-id",
+		},
+		Condition: `device.output1 == 0 && (meter["power"] * 2 > 100 || meter.mode === "manual")`,
+	}
+
+	t.Run("Compile", func(t *testing.T) {
+		compiled, err := condition.Compile()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		refs := compiled.References()
+		if len(refs) != 3 {
+			t.Fatalf("expected 3 references, got %d", len(refs))
+		}
+		if refs[0].DeviceId != "my-device-id" || refs[0].Property != "output1" {
+			t.Fatalf("unexpected first reference %+v", refs[0])
+		}
+	})
+
+	t.Run("Evaluate", func(t *testing.T) {
+		cases := []struct {
+			states   map[string]map[string]any
+			expected bool
+		}{
+			{map[string]map[string]any{"my-device-id": {"output1": 0}, "my-meter-id": {"power": 60}}, true},
+			{map[string]map[string]any{"my-device-id": {"output1": 0}, "my-meter-id": {"power": 10, "mode": "manual"}}, true},
+			{map[string]map[string]any{"my-device-id": {"output1": 1}, "my-meter-id": {"power": 60}}, false},
+			{map[string]map[string]any{"my-device-id": {"output1": "0"}, "my-meter-id": {"power": 10}}, false},
+		}
+
+		for i, c := range cases {
+			res, err := condition.Evaluate(c.states)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res != c.expected {
+				t.Fatalf("case %d: expected %v, got %v", i, c.expected, res)
+			}
+		}
+	})
+
+	t.Run("Missing State", func(t *testing.T) {
+		_, err := condition.Evaluate(map[string]map[string]any{"my-device-id": {"output1": 0}})
+		if err == nil {
+			t.Fatal("expected error for missing device state")
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, expr := range []string{
+			"device.output1 ==",
+			"(device.output1 == 0",
+			"device == 0",
+			"other.output1 == 0",
+			"device.output1 = 0",
+			"'unterminated",
+		} {
+			c := goplatform.RuleCondition{Devices: condition.Devices, Condition: expr}
+			if _, err := c.Compile(); err == nil {
+				t.Fatalf("expected error for %q", expr)
+			}
+		}
+	})
+}
+
+func loadRule(t *testing.T) goplatform.Rule {
+	t.Helper()
+
+	b, err := os.ReadFile("./mock/rule.json")
+	if err != nil {
+		t.Fatalf("errore nel leggere il file: %v", err)
+	}
+
+	var payload struct {
+		Data goplatform.Rule `json:"data"`
+	}
+	if err := json.Unmarshal(b, &payload); err != nil {
+		t.Fatal(err)
+	}
+
+	return payload.Data
+}
+
+func TestRuleSimulator(t *testing.T) {
+	deviceId := "a62260a7-8d12-4048-867a-43f704765402"
+	property := "output1"
+
+	input := goplatform.RuleSimulationInput{
+		Trigger: goplatform.RuleTrigger{
+			Type:     goplatform.RULE_TRIGGER_TELEMETRY,
+			DeviceId: &deviceId,
+			Property: &property,
+		},
+		Value:  0,
+		States: map[string]map[string]any{deviceId: {"output1": 1}},
+	}
+
+	t.Run("Golden", func(t *testing.T) {
+		trace, err := loadRule(t).Simulate(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b, err := json.MarshalIndent(trace, "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		golden, err := os.ReadFile("./mock/rule_trace.golden.json")
+		if err != nil {
+			t.Fatalf("errore nel leggere il file: %v", err)
+		}
+
+		if string(b) != strings.TrimSpace(string(golden)) {
+			t.Fatalf("trace does not match golden file, got:\n%s", b)
+		}
+	})
+
+	t.Run("Else Branch", func(t *testing.T) {
+		in := input
+		in.Value = 1
+
+		trace, err := loadRule(t).Simulate(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if trace.Branch != goplatform.RULE_BRANCH_ELSE_ACTIONS {
+			t.Fatalf("expected branch to be 'elseActions', got '%s'", trace.Branch)
+		}
+		if trace.Steps[0].Command.Parameters[0]["value"] != float64(1) {
+			t.Fatalf("expected command value to be 1, got %v", trace.Steps[0].Command.Parameters[0]["value"])
+		}
+	})
+
+	t.Run("No Matching Trigger", func(t *testing.T) {
+		in := input
+		other := "output2"
+		in.Trigger.Property = &other
+
+		trace, err := loadRule(t).Simulate(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if trace.Executed {
+			t.Fatal("expected rule not to be executed")
+		}
+	})
+
+	t.Run("Sequence", func(t *testing.T) {
+		rule := loadRule(t)
+		delay := int64(5000)
+		rule.Actions = append([]goplatform.RuleAction{{Type: goplatform.RULE_ACTION_DELAY, Time: &delay}}, rule.Actions...)
+
+		start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		inputs := []goplatform.RuleSimulationInput{input, input, input}
+		inputs[0].At = start
+		inputs[1].At = start.Add(time.Second)
+		inputs[2].At = start.Add(10 * time.Second)
+
+		traces, err := rule.SimulateSequence(inputs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !traces[0].Executed || traces[1].Executed || !traces[2].Executed {
+			t.Fatalf("unexpected executions %v %v %v", traces[0].Executed, traces[1].Executed, traces[2].Executed)
+		}
+		if traces[0].Steps[1].OffsetMs != 5000 {
+			t.Fatalf("expected command offset to be 5000, got %d", traces[0].Steps[1].OffsetMs)
+		}
+
+		rule.AllowConcurrent = true
+		traces, err = rule.SimulateSequence(inputs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !traces[1].Executed {
+			t.Fatal("expected concurrent execution")
+		}
+	})
+
+	t.Run("Runtime", func(t *testing.T) {
+		in := input
+		in.Runtime = goplatform.RULE_MODE_CLOUD
+
+		trace, err := loadRule(t).Simulate(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if trace.Executed {
+			t.Fatal("expected edge rule to be skipped on cloud runtime")
+		}
+	})
+}
+
+func TestRuleVariants(t *testing.T) {
+	t.Run("Constructors", func(t *testing.T) {
+		deviceId := "my-device-id"
+		actions := []goplatform.RuleAction{
+			goplatform.WasmAction("check", "rust", "fn main() {}"),
+			goplatform.LogAction("hello"),
+			goplatform.WebhookAction("https://example.com/hook", `{"ok":true}`),
+			goplatform.CommandAction(goplatform.CommandRequest{Name: "set", ProjectId: PROJECT_ID, DeviceId: &deviceId}),
+			goplatform.DelayAction(2 * time.Second),
+		}
+
+		for _, action := range actions {
+			if err := action.Validate(); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		variant, err := actions[4].Variant()
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch v := variant.(type) {
+		case goplatform.DelayRuleAction:
+			if v.Duration != 2*time.Second {
+				t.Fatalf("expected duration to be 2s, got %s", v.Duration)
+			}
+		default:
+			t.Fatalf("expected a delay action, got %T", v)
+		}
+
+		triggers := []goplatform.RuleTrigger{
+			goplatform.PropertyTrigger(deviceId, "output1"),
+			goplatform.CronTrigger("*/5 * * * *"),
+			goplatform.TopicTrigger("sensors/+/alarm"),
+		}
+		for _, trigger := range triggers {
+			if err := trigger.Validate(); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := loadRule(t).Validate(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Validate", func(t *testing.T) {
+		value := "hello"
+		uri := "https://example.com"
+
+		if err := (goplatform.RuleAction{Type: goplatform.RULE_ACTION_LOG}).Validate(); err == nil {
+			t.Fatal("expected error for missing value")
+		}
+		if err := (goplatform.RuleAction{Type: goplatform.RULE_ACTION_LOG, Value: &value, Uri: &uri}).Validate(); err == nil {
+			t.Fatal("expected error for irrelevant uri")
+		}
+		if err := (goplatform.RuleAction{Type: "unknown"}).Validate(); err == nil {
+			t.Fatal("expected error for unknown type")
+		}
+		if err := (goplatform.RuleTrigger{Type: goplatform.RULE_TRIGGER_CRON, Topic: &value}).Validate(); err == nil {
+			t.Fatal("expected error for cron trigger without crontab")
+		}
+		if _, err := (goplatform.RuleAction{Type: goplatform.RULE_ACTION_DELAY}).Variant(); err == nil {
+			t.Fatal("expected error for invalid variant")
+		}
+	})
+}
+
+func TestRuleFile(t *testing.T) {
+	rule := loadRule(t)
+
+	for _, format := range []goplatform.RuleFileFormat{goplatform.RULE_FILE_YAML, goplatform.RULE_FILE_JSON} {
+		t.Run(string(format), func(t *testing.T) {
+			b, err := goplatform.MarshalRuleFile(rule, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(string(b), `"projectId": "my-project-id"`) || strings.Contains(string(b), "projectId: my-project-id") {
+				t.Fatalf("expected projectId to be stripped, got:\n%s", b)
+			}
+
+			decoded, err := goplatform.UnmarshalRuleFile(b, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			again, err := goplatform.MarshalRuleFile(decoded, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(again) != string(b) {
+				t.Fatalf("expected stable output, got:\n%s\nthen:\n%s", b, again)
+			}
+		})
+	}
+
+	t.Run("Invalid", func(t *testing.T) {
+		if _, err := goplatform.UnmarshalRuleFile([]byte("name: test\ntriggers: []\n"), goplatform.RULE_FILE_YAML); err == nil {
+			t.Fatal("expected error for rule without triggers")
+		}
+		if _, err := goplatform.UnmarshalRuleFile([]byte("name: test\nunknown: 1\n"), goplatform.RULE_FILE_YAML); err == nil {
+			t.Fatal("expected error for unknown field")
+		}
+	})
+}
+
+func TestWasmAction(t *testing.T) {
+	// A module exporting an empty function named run
+	module := []byte{
+		0x00, 'a', 's', 'm', 0x01, 0x00, 0x00, 0x00,
+		0x01, 0x04, 0x01, 0x60, 0x00, 0x00,
+		0x03, 0x02, 0x01, 0x00,
+		0x07, 0x07, 0x01, 0x03, 'r', 'u', 'n', 0x00, 0x00,
+		0x0a, 0x04, 0x01, 0x02, 0x00, 0x0b,
+	}
+
+	path := filepath.Join(t.TempDir(), "rule.wasm")
+	if err := os.WriteFile(path, module, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("From file", func(t *testing.T) {
+		action, err := goplatform.WasmActionFromFile("check", "rust", path, goplatform.WasmActionOptions{
+			Exports: []string{"run"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := action.Validate(); err != nil {
+			t.Fatal(err)
+		}
+
+		variant, err := action.Variant()
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := variant.(goplatform.WasmRuleAction).Module()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(decoded) != string(module) {
+			t.Fatal("expected decoded binary to match the module")
+		}
+	})
+
+	t.Run("Invalid module", func(t *testing.T) {
+		if _, err := goplatform.WasmActionFromModule("check", "rust", module, goplatform.WasmActionOptions{
+			Exports: []string{"main"},
+		}); err == nil || !strings.Contains(err.Error(), "missing export main") {
+			t.Fatalf("expected missing export error got %v", err)
+		}
+		if _, err := goplatform.WasmActionFromModule("check", "rust", []byte("fn main() {}")); err == nil {
+			t.Fatal("expected error for a non wasm binary")
+		}
+		if _, err := goplatform.WasmActionFromModule("check", "rust", module[:len(module)-2]); err == nil {
+			t.Fatal("expected error for a truncated module")
+		}
+	})
+
+	t.Run("Uppercase hash", func(t *testing.T) {
+		action, err := goplatform.WasmActionFromModule("check", "rust", module)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hash := strings.ToUpper(*action.Hash)
+		action.Hash = &hash
+
+		if err := action.Validate(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Hash mismatch", func(t *testing.T) {
+		action, err := goplatform.WasmActionFromModule("check", "rust", module)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hash := strings.Repeat("0", 64)
+		action.Hash = &hash
+		topic := "my-topic"
+
+		b, err := json.Marshal(goplatform.Rule{
+			Name:     "wasm",
+			Status:   goplatform.RULE_STATUS_ENABLED,
+			Mode:     goplatform.RULE_MODE_CLOUD,
+			Triggers: []goplatform.RuleTrigger{{Type: goplatform.RULE_TRIGGER_TOPIC, Topic: &topic}},
+			Actions:  []goplatform.RuleAction{action},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := goplatform.UnmarshalRuleFile(b, goplatform.RULE_FILE_JSON); err == nil || !strings.Contains(err.Error(), "does not match") {
+			t.Fatalf("expected hash mismatch error got %v", err)
+		}
+	})
+}
@@ -0,0 +1,173 @@
+package goplatform_test
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ApioIoT/goplatform/v2"
+)
+
+const stateDevice = `{
+  "uuid": "my-device-id",
+  "name": "sensor",
+  "state": {
+    "count": 12,
+    "temperature": "21.5",
+    "enabled": 1,
+    "label": 42,
+    "raw": {"a": 1}
+  },
+  "stateUpdatedAt": {
+    "count": "2024-08-09T13:22:38.879Z",
+    "temperature": 1723209758879,
+    "enabled": "+056576-05-05T09:27:59.181Z",
+    "label": "never"
+  },
+  "deviceType": {
+    "name": "sensor",
+    "properties": {
+      "count": {"type": "integer", "uom": "pcs", "precision": 2},
+      "temperature": {"type": "float"},
+      "enabled": {"type": "boolean"},
+      "label": {"type": "string"}
+    }
+  }
+}`
+
+func TestDeviceState(t *testing.T) {
+	var device goplatform.Device
+	if err := json.Unmarshal([]byte(stateDevice), &device); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("StateValue", func(t *testing.T) {
+		cases := []struct {
+			name     string
+			expected any
+		}{
+			{"count", int64(12)},
+			{"temperature", 21.5},
+			{"enabled", true},
+			{"label", "42"},
+		}
+
+		for _, c := range cases {
+			value, ok := device.StateValue(c.name)
+			if !ok {
+				t.Fatalf("expected %s in state", c.name)
+			}
+			if value != c.expected {
+				t.Fatalf("expected %s = %v (%T) got %v (%T)", c.name, c.expected, c.expected, value, value)
+			}
+		}
+
+		if _, ok := device.StateValue("missing"); ok {
+			t.Fatal("expected missing property not found")
+		}
+	})
+
+	t.Run("StateAs", func(t *testing.T) {
+		count, err := goplatform.StateAs[int](device, "count")
+		if err != nil || count != 12 {
+			t.Fatalf("expected 12 got %v (%v)", count, err)
+		}
+
+		temperature, err := goplatform.StateAs[float64](device, "temperature")
+		if err != nil || temperature != 21.5 {
+			t.Fatalf("expected 21.5 got %v (%v)", temperature, err)
+		}
+
+		label, err := goplatform.StateAs[string](device, "label")
+		if err != nil || label != "42" {
+			t.Fatalf("expected 42 got %v (%v)", label, err)
+		}
+
+		raw, err := goplatform.StateAs[struct{ A int }](device, "raw")
+		if err != nil || raw.A != 1 {
+			t.Fatalf("expected {1} got %v (%v)", raw, err)
+		}
+
+		if _, err := goplatform.StateAs[int](device, "temperature"); err == nil {
+			t.Fatal("expected error converting 21.5 to int")
+		}
+
+		if _, err := goplatform.StateAs[bool](device, "missing"); err == nil {
+			t.Fatal("expected error on missing property")
+		}
+	})
+
+	t.Run("StateTime", func(t *testing.T) {
+		expected := time.Date(2024, 8, 9, 13, 22, 38, 879000000, time.UTC)
+
+		for _, name := range []string{"count", "temperature"} {
+			at, ok := device.StateTime(name)
+			if !ok || !at.Equal(expected) {
+				t.Fatalf("expected %s updated at %v got %v", name, expected, at)
+			}
+		}
+
+		at, ok := device.StateTime("enabled")
+		if !ok || !at.Equal(time.UnixMicro(1723209758879181)) {
+			t.Fatalf("expected microsecond timestamp got %v", at)
+		}
+
+		if _, ok := device.StateTime("label"); ok {
+			t.Fatal("expected invalid timestamp to be dropped")
+		}
+	})
+
+	t.Run("Mock devices", func(t *testing.T) {
+		b, err := os.ReadFile("./mock/devices.json")
+		if err != nil {
+			t.Fatalf("errore nel leggere il file: %v", err)
+		}
+
+		var payload struct {
+			Data []goplatform.Device `json:"data"`
+		}
+		if err := json.Unmarshal(b, &payload); err != nil {
+			t.Fatal(err)
+		}
+
+		at, ok := payload.Data[1].StateTime("projectId")
+		if !ok || at.Year() != 2024 {
+			t.Fatalf("expected 2024 timestamp got %v", at)
+		}
+
+		// The platform names floating point properties double
+		deviceType := payload.Data[1].DeviceType
+		value, err := deviceType.CoerceProperty("input1", "1")
+		if err != nil || value != float64(1) {
+			t.Fatalf("expected double property coerced to 1 got %v (%T, %v)", value, value, err)
+		}
+
+		deviceType.Commands = map[string]goplatform.DeviceTypeCommand{
+			"set": {Parameters: map[string]goplatform.DeviceTypeCommandParameter{
+				"value": {Type: deviceType.Properties["input1"].Type, Required: true},
+			}},
+		}
+		if err := deviceType.ValidateCommand("set", goplatform.CommandParameter{"value": 1.5}); err != nil {
+			t.Fatal(err)
+		}
+		if err := deviceType.ValidateCommand("set", goplatform.CommandParameter{"value": "on"}); err == nil {
+			t.Fatal("expected error for a non numeric double parameter")
+		}
+	})
+
+	t.Run("Unknown property fields", func(t *testing.T) {
+		property := device.DeviceType.Properties["count"]
+		if property.Uom != "pcs" || property.Extras["precision"] != float64(2) {
+			t.Fatalf("expected uom and precision to be decoded got %+v", property)
+		}
+
+		b, err := json.Marshal(property)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != `{"precision":2,"type":"integer","uom":"pcs"}` {
+			t.Fatalf("expected unknown fields to be kept got %s", b)
+		}
+	})
+}
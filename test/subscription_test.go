@@ -0,0 +1,138 @@
+package goplatform_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ApioIoT/goplatform/v2"
+	"github.com/h2non/gock"
+)
+
+func TestSubscribe(t *testing.T) {
+	defer gock.Off()
+
+	gock.New(API_URI).
+		Get("/projects/"+PROJECT_ID+"/").
+		MatchHeader("Authorization", "apiKey "+API_KEY).
+		Reply(200).
+		JSON(map[string]any{"status": true, "data": map[string]any{"uuid": PROJECT_ID}})
+
+	gock.New(API_URI).
+		Get("/projects/"+PROJECT_ID+"/subscribe").
+		MatchParam("types", "state,command").
+		MatchHeader("Accept", "text/event-stream").
+		Reply(200).
+		SetHeader("Content-Type", "text/event-stream").
+		BodyString(": keep-alive\n\n" +
+			"id: 1\nevent: state\ndata: {\"deviceId\":\"" + DEVICE_ID + "\",\"state\":{\"temperature\":21.5}}\n\n" +
+			"id: 2\nevent: event\ndata: {\"event\":{\"type\":\"Notification\"}}\n\n" +
+			"id: 3\ndata: {\"type\":\"command\",\n" +
+			"data: \"command\":{\"uuid\":\"my-command-id\",\"status\":\"completed\"}}\n\n")
+
+	gock.New(API_URI).
+		Get("/projects/"+PROJECT_ID+"/subscribe").
+		MatchHeader("Last-Event-ID", "3").
+		Reply(200).
+		SetHeader("Content-Type", "text/event-stream").
+		BodyString("id: 4\nevent: state\ndata: {\"deviceId\":\"" + DEVICE_ID + "\",\"state\":{\"temperature\":22}}\n\n")
+
+	gock.New(API_URI).
+		Get("/projects/" + PROJECT_ID + "/subscribe").
+		Persist().
+		Reply(http.StatusUnauthorized)
+
+	platform := goplatform.New(goplatform.Config{
+		Uri:    API_URI,
+		ApiKey: API_KEY,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	project, err := platform.GetProject(ctx, PROJECT_ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var errs []error
+	notifications, err := project.Subscribe(ctx, goplatform.SubscriptionOptions{
+		Types:          []goplatform.NotificationType{goplatform.NOTIFICATION_STATE, goplatform.NOTIFICATION_COMMAND},
+		ReconnectDelay: time.Millisecond,
+		OnError:        func(err error) { errs = append(errs, err) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var received []goplatform.Notification
+	for n := range notifications {
+		received = append(received, n)
+	}
+
+	if len(received) != 3 {
+		t.Fatalf("expected 3 notifications got %d: %+v", len(received), received)
+	}
+
+	if n := received[0]; n.Id != "1" || n.Type != goplatform.NOTIFICATION_STATE || n.DeviceId != DEVICE_ID || n.State["temperature"] != 21.5 {
+		t.Fatalf("expected state notification got %+v", n)
+	}
+	if n := received[1]; n.Type != goplatform.NOTIFICATION_COMMAND || n.Command == nil || n.Command.Status != goplatform.CMD_STATUS_COMPLETED {
+		t.Fatalf("expected command notification got %+v", n)
+	}
+	if n := received[2]; n.Id != "4" || n.State["temperature"] != 22.0 {
+		t.Fatalf("expected resumed state notification got %+v", n)
+	}
+
+	if ctx.Err() != nil {
+		t.Fatal("expected subscription to stop on unauthorized")
+	}
+	if len(errs) == 0 {
+		t.Fatal("expected reconnection errors")
+	}
+}
+
+func TestSubscribeDeviceIds(t *testing.T) {
+	defer gock.Off()
+
+	// The server ignores the deviceIds parameter
+	gock.New(API_URI).
+		Get("/projects/"+PROJECT_ID+"/subscribe").
+		MatchParam("deviceIds", DEVICE_ID).
+		Reply(200).
+		SetHeader("Content-Type", "text/event-stream").
+		BodyString("id: 1\nevent: state\ndata: {\"deviceId\":\"my-other-device-id\",\"state\":{\"temperature\":18}}\n\n" +
+			"id: 2\nevent: state\ndata: {\"deviceId\":\"" + DEVICE_ID + "\",\"state\":{\"temperature\":21.5}}\n\n" +
+			"id: 3\nevent: event\ndata: {\"event\":{\"type\":\"Notification\"}}\n\n")
+
+	gock.New(API_URI).
+		Get("/projects/" + PROJECT_ID + "/subscribe").
+		Persist().
+		Reply(http.StatusUnauthorized)
+
+	platform := goplatform.New(goplatform.Config{
+		Uri:    API_URI,
+		ApiKey: API_KEY,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	notifications, err := platform.Project(PROJECT_ID).Subscribe(ctx, goplatform.SubscriptionOptions{
+		DeviceIds:      []string{DEVICE_ID},
+		ReconnectDelay: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var received []goplatform.Notification
+	for n := range notifications {
+		received = append(received, n)
+	}
+
+	if len(received) != 1 || received[0].DeviceId != DEVICE_ID {
+		t.Fatalf("expected only notifications of %s got %+v", DEVICE_ID, received)
+	}
+}
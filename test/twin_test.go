@@ -0,0 +1,172 @@
+package goplatform_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/ApioIoT/goplatform/v2"
+	"github.com/h2non/gock"
+)
+
+const twinDevice = `{
+  "uuid": "my-device-id",
+  "projectId": "my-project-id",
+  "deviceTypeId": "my-devicetype-id",
+  "nodeId": "my-node-id",
+  "name": "thermostat",
+  "state": {"setpoint": 10, "temperature": 20, "light": false, "mode": "auto"},
+  "desiredState": {"setpoint": 12, "temperature": 25, "light": true, "mode": "auto"},
+  "deviceType": {
+    "uuid": "my-devicetype-id",
+    "name": "thermostat",
+    "properties": {
+      "setpoint": {"type": "integer"},
+      "temperature": {"type": "float"},
+      "light": {"type": "boolean"},
+      "mode": {"type": "string"}
+    },
+    "protocols": {
+      "modbus": {
+        "endianness": "ABCD",
+        "registers": [
+          {"register": 10, "read": true, "write": true, "words": 1, "type": "uint", "properties": [{"index": 0, "name": "setpoint"}]},
+          {"register": 11, "read": true, "write": false, "words": 1, "type": "int", "properties": [{"index": 0, "name": "temperature"}]}
+        ]
+      },
+      "knx": {
+        "properties": {
+          "light": {"address": "1/2/3", "sendDPT": "1.001", "receiveDPT": "1.001"}
+        }
+      }
+    }
+  }
+}`
+
+func TestDeviceTwin(t *testing.T) {
+	defer gock.Off()
+
+	gock.New(API_URI).
+		Get("/projects/" + PROJECT_ID + "/devices/" + DEVICE_ID).
+		Persist().
+		Reply(200).
+		BodyString(`{"status":true,"data":` + twinDevice + `}`)
+
+	gock.New(API_URI).
+		Patch("/projects/" + PROJECT_ID + "/devices/" + DEVICE_ID).
+		AddMatcher(func(req *http.Request, ereq *gock.Request) (bool, error) {
+			b, err := io.ReadAll(req.Body)
+			if err != nil {
+				return false, err
+			}
+			// Only the desired state is sent
+			var body map[string]map[string]any
+			if err := json.Unmarshal(b, &body); err != nil || len(body) != 1 {
+				return false, err
+			}
+			// Only the changed properties, removals as null
+			desired := body["desiredState"]
+			mode, hasMode := desired["mode"]
+			return len(desired) == 2 && desired["setpoint"] == 15.0 && hasMode && mode == nil, nil
+		}).
+		Reply(200).
+		BodyString(`{"status":true,"data":{"uuid":"my-device-id","projectId":"my-project-id","desiredState":{"setpoint":15}}}`)
+
+	var sent []goplatform.Command
+	gock.New(API_URI).
+		Post("/projects/" + PROJECT_ID + "/commands").
+		Persist().
+		AddMatcher(func(req *http.Request, ereq *gock.Request) (bool, error) {
+			var command goplatform.Command
+			if err := json.NewDecoder(req.Body).Decode(&command); err != nil {
+				return false, err
+			}
+			sent = append(sent, command)
+			return true, nil
+		}).
+		Reply(200)
+
+	platform := goplatform.New(goplatform.Config{
+		Uri:    API_URI,
+		ApiKey: API_KEY,
+	})
+	ctx := context.Background()
+
+	device, err := platform.Project(PROJECT_ID).GetDevice(ctx, DEVICE_ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Twin", func(t *testing.T) {
+		twin := device.Twin()
+		if len(twin.Delta) != 3 {
+			t.Fatalf("expected 3 delta properties got %v", twin.Delta)
+		}
+		if _, ok := twin.Delta["mode"]; ok {
+			t.Fatal("expected mode to be in sync")
+		}
+	})
+
+	t.Run("Reconcile", func(t *testing.T) {
+		res, err := device.Reconcile(ctx, goplatform.ReconcileOptions{DryRun: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(sent) != 0 {
+			t.Fatal("expected dry run not to send commands")
+		}
+		for _, command := range res.Commands {
+			if command.NodeId == nil || *command.NodeId != "my-node-id" {
+				t.Fatalf("expected dry run commands to target the node got %+v", command)
+			}
+		}
+
+		if len(res.Commands) != 2 {
+			t.Fatalf("expected 2 commands got %+v", res.Commands)
+		}
+
+		light := res.Commands[0]
+		if light.Name != goplatform.TWIN_COMMAND_KNX_WRITE || light.Parameters[0]["address"] != "1/2/3" || light.Parameters[0]["data"] != "01" {
+			t.Fatalf("expected knx write of light got %+v", light)
+		}
+
+		setpoint := res.Commands[1]
+		words, _ := setpoint.Parameters[0]["words"].([]uint16)
+		if setpoint.Name != goplatform.TWIN_COMMAND_MODBUS_WRITE || setpoint.Parameters[0]["address"] != uint16(10) || len(words) != 1 || words[0] != 12 {
+			t.Fatalf("expected modbus write of setpoint got %+v", setpoint)
+		}
+
+		if len(res.Skipped) != 1 || res.Skipped[0].Property != "temperature" {
+			t.Fatalf("expected temperature to be skipped got %+v", res.Skipped)
+		}
+
+		res, err = device.Reconcile(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(sent) != 2 || sent[0].Uuid != res.Commands[0].Uuid || *sent[1].DeviceId != DEVICE_ID {
+			t.Fatalf("expected 2 commands sent got %+v", sent)
+		}
+	})
+
+	t.Run("SetDesiredState", func(t *testing.T) {
+		var requests []string
+		gock.Observe(func(req *http.Request, mock gock.Mock) {
+			requests = append(requests, req.Method)
+		})
+		defer gock.Observe(nil)
+
+		updated, err := device.SetDesiredState(ctx, map[string]any{"setpoint": 15, "mode": nil})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(requests) != 1 || requests[0] != http.MethodPatch {
+			t.Fatalf("expected a single PATCH got %v", requests)
+		}
+		if updated.DesiredState["setpoint"] != 15.0 {
+			t.Fatalf("expected desired setpoint 15 got %v", updated.DesiredState)
+		}
+	})
+}
@@ -1,14 +1,24 @@
 package goplatform_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"maps"
+	"net"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"os"
+	"path/filepath"
 
 	"github.com/ApioIoT/goplatform/v2"
 	"github.com/h2non/gock"
@@ -39,6 +49,16 @@ func TestUnit(t *testing.T) {
 		MatchHeader("Authorization", fmt.Sprintf("apiKey %s", API_KEY)).
 		Reply(200)
 
+	gock.New(API_URI).
+		AddMatcher(func(req *http.Request, ereq *gock.Request) (bool, error) {
+			return req.Method == "GET" && req.URL.Path == "/projects", nil
+		}).
+		Persist().
+		MatchHeader("Authorization", "apiKey invalid-api-key").
+		Reply(401).
+		Type("application/json").
+		BodyString(`{"status":false,"error":{"name":"Unauthorized","message":"invalid api key"}}`)
+
 	projects, err := os.ReadFile("./mock/projects.json")
 	if err != nil {
 		t.Fatalf("errore nel leggere il file: %v", err)
@@ -166,6 +186,17 @@ func TestUnit(t *testing.T) {
 		Type("application/json").
 		BodyString(string(device))
 
+	ruleDeviceUrl, _ := url.JoinPath("/projects", PROJECT_ID, "devices", "a62260a7-8d12-4048-867a-43f704765402")
+	gock.New(API_URI).
+		AddMatcher(func(req *http.Request, ereq *gock.Request) (bool, error) {
+			return req.Method == "GET" && req.URL.Path == ruleDeviceUrl, nil
+		}).
+		Persist().
+		MatchHeader("Authorization", fmt.Sprintf("apiKey %s", API_KEY)).
+		Reply(200).
+		Type("application/json").
+		BodyString(strings.Replace(string(device), `"uuid": "my-device-id"`, `"uuid": "a62260a7-8d12-4048-867a-43f704765402"`, 1))
+
 	devicetypes, err := os.ReadFile("./mock/devicetypes.json")
 	if err != nil {
 		t.Fatalf("errore nel leggere il file: %v", err)
@@ -207,8 +238,179 @@ func TestUnit(t *testing.T) {
 		MatchHeader("Authorization", fmt.Sprintf("apiKey %s", API_KEY)).
 		Reply(200)
 
+	gock.New(API_URI).
+		AddMatcher(func(req *http.Request, ereq *gock.Request) (bool, error) {
+			return req.Method == "DELETE" && strings.HasPrefix(req.URL.Path, "/projects/"+PROJECT_ID+"/"), nil
+		}).
+		Persist().
+		MatchHeader("Authorization", fmt.Sprintf("apiKey %s", API_KEY)).
+		Reply(200).
+		Type("application/json").
+		BodyString(`{"status":true}`)
+
+	gock.New(API_URI).
+		AddMatcher(func(req *http.Request, ereq *gock.Request) (bool, error) {
+			return req.Method == "PUT" && req.URL.Path == ruleUrl, nil
+		}).
+		Persist().
+		MatchHeader("Authorization", fmt.Sprintf("apiKey %s", API_KEY)).
+		Reply(200).
+		Type("application/json").
+		BodyString(string(rule))
+
+	gock.New(API_URI).
+		AddMatcher(func(req *http.Request, ereq *gock.Request) (bool, error) {
+			return req.Method == "POST" && req.URL.Path == devicetypesUrl, nil
+		}).
+		Persist().
+		MatchHeader("Authorization", fmt.Sprintf("apiKey %s", API_KEY)).
+		Reply(200).
+		Type("application/json").
+		BodyString(`{"status":true,"data":{"uuid":"new-type-id","name":"new-type"}}`)
+
+	gock.New(API_URI).
+		AddMatcher(func(req *http.Request, ereq *gock.Request) (bool, error) {
+			if req.Method != "POST" || req.URL.Path != devicesUrl {
+				return false, nil
+			}
+			b, err := io.ReadAll(req.Body)
+			if err != nil {
+				return false, err
+			}
+			req.Body = io.NopCloser(bytes.NewReader(b))
+			return strings.Contains(string(b), `"deviceTypeId":"new-type-id"`) && strings.Contains(string(b), `"nodeId":"my-node-id"`), nil
+		}).
+		Persist().
+		MatchHeader("Authorization", fmt.Sprintf("apiKey %s", API_KEY)).
+		Reply(200).
+		Type("application/json").
+		BodyString(`{"status":true,"data":{"uuid":"new-device-id","name":"new-device"}}`)
+
 	// --- TESTS
 
+	t.Run("Project handle", func(t *testing.T) {
+		platform := goplatform.New(goplatform.Config{
+			Uri:    API_URI,
+			ApiKey: API_KEY,
+		})
+
+		project := platform.Project(PROJECT_ID)
+
+		devices, err := project.GetDevices(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(devices) == 0 {
+			t.Fatal("expected devices from project handle")
+		}
+
+		if project.Name != "" {
+			t.Fatalf("expected empty handle got name %s", project.Name)
+		}
+
+		loaded, err := project.Load(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if loaded.Uuid != PROJECT_ID || loaded.Name == "" {
+			t.Fatalf("expected loaded project %s got %+v", PROJECT_ID, loaded)
+		}
+	})
+
+	t.Run("Relationships", func(t *testing.T) {
+		platform := goplatform.New(goplatform.Config{
+			Uri:    API_URI,
+			ApiKey: API_KEY,
+		})
+		project := platform.Project(PROJECT_ID)
+		ctx := context.Background()
+
+		device, err := project.GetDevice(ctx, DEVICE_ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		node, err := device.Node(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if node.Uuid != NODE_ID {
+			t.Fatalf("expected node %s got %s", NODE_ID, node.Uuid)
+		}
+
+		nodeDevices, err := node.Devices(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(nodeDevices) != 2 {
+			t.Fatalf("expected 2 devices on node got %d", len(nodeDevices))
+		}
+
+		deviceType, err := device.Type(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if deviceType.Uuid != device.DeviceTypeID {
+			t.Fatalf("expected embedded device type %s got %s", device.DeviceTypeID, deviceType.Uuid)
+		}
+
+		typeDevices, err := deviceType.Devices(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, d := range typeDevices {
+			if d.DeviceTypeID != deviceType.Uuid {
+				t.Fatalf("expected devices of type %s got %s", deviceType.Uuid, d.DeviceTypeID)
+			}
+		}
+
+		rule, err := project.GetRule(ctx, RULE_ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		referenced, err := rule.ReferencedDevices(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(referenced) != 1 || referenced[0].Uuid != "a62260a7-8d12-4048-867a-43f704765402" {
+			t.Fatalf("expected 1 referenced device got %+v", referenced)
+		}
+
+		if _, err := (goplatform.Device{Uuid: "detached"}).Node(ctx); err == nil {
+			t.Fatal("expected error on a device not fetched from a platform")
+		}
+	})
+
+	t.Run("Shared Client", func(t *testing.T) {
+		client := goplatform.NewClient(goplatform.Config{
+			Uri:    API_URI,
+			ApiKey: API_KEY,
+		})
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 10)
+		for range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				project, err := client.GetProject(context.Background(), PROJECT_ID)
+				if err != nil {
+					errs <- err
+					return
+				}
+				if _, err := project.GetDevices(context.Background()); err != nil {
+					errs <- err
+				}
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			t.Fatal(err)
+		}
+	})
+
 	t.Run("Invalid API Key", func(t *testing.T) {
 		platform := goplatform.New(goplatform.Config{
 			Uri:    API_URI,
@@ -218,6 +420,14 @@ func TestUnit(t *testing.T) {
 		if err == nil {
 			t.Fatal("expected error with invalid API key")
 		}
+
+		var apiErr *goplatform.APIError
+		if !errors.As(err, &apiErr) {
+			t.Fatalf("expected *goplatform.APIError got %T", err)
+		}
+		if apiErr.StatusCode != http.StatusUnauthorized || apiErr.Name != "Unauthorized" {
+			t.Fatalf("expected 401 Unauthorized got %d %s", apiErr.StatusCode, apiErr.Name)
+		}
 	})
 
 	t.Run("Empty API Key", func(t *testing.T) {
@@ -526,6 +736,155 @@ func TestUnit(t *testing.T) {
 		}
 	})
 
+	t.Run("ExportRules", func(t *testing.T) {
+		platform := goplatform.New(goplatform.Config{
+			Uri:    API_URI,
+			ApiKey: API_KEY,
+		})
+		project, err := platform.GetProject(context.Background(), PROJECT_ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		dir := t.TempDir()
+		if err := goplatform.ExportRules(context.Background(), project, dir); err != nil {
+			t.Fatal(err)
+		}
+
+		rules, err := goplatform.ImportRules(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rules) != 1 {
+			t.Fatalf("expected 1 rule, got %d", len(rules))
+		}
+		if rules[0].Uuid != RULE_ID {
+			t.Fatalf("expected rule Uuid to be '%s', got '%s'", RULE_ID, rules[0].Uuid)
+		}
+		if rules[0].ProjectId != "" {
+			t.Fatalf("expected projectId to be stripped, got '%s'", rules[0].ProjectId)
+		}
+
+		// An export made before the rule was renamed
+		renamed := rules[0]
+		renamed.Name = "old name"
+		b, err := goplatform.MarshalRuleFile(renamed, goplatform.RULE_FILE_YAML)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "old-name.yaml"), b, 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := goplatform.ExportRules(context.Background(), project, dir); err != nil {
+			t.Fatal(err)
+		}
+
+		rules, err = goplatform.ImportRules(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rules) != 1 {
+			t.Fatalf("expected the renamed rule file to be removed, got %d rules", len(rules))
+		}
+	})
+
+	t.Run("Plan and Apply", func(t *testing.T) {
+		platform := goplatform.New(goplatform.Config{
+			Uri:    API_URI,
+			ApiKey: API_KEY,
+		})
+		project, err := platform.GetProject(context.Background(), PROJECT_ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		desired, err := os.ReadFile("./mock/state.yaml")
+		if err != nil {
+			t.Fatalf("errore nel leggere il file: %v", err)
+		}
+
+		state, err := goplatform.ParseProjectState(desired)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		plan, err := project.Plan(context.Background(), state, goplatform.PlanOptions{Prune: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := []struct {
+			action goplatform.PlanAction
+			kind   goplatform.ResourceKind
+			name   string
+		}{
+			{goplatform.PLAN_CREATE, goplatform.RESOURCE_DEVICE_TYPE, "new-type"},
+			{goplatform.PLAN_CREATE, goplatform.RESOURCE_DEVICE, "new-device"},
+			{goplatform.PLAN_UPDATE, goplatform.RESOURCE_RULE, "test"},
+			{goplatform.PLAN_DELETE, goplatform.RESOURCE_DEVICE, "seneca z-d-in"},
+			{goplatform.PLAN_DELETE, goplatform.RESOURCE_DEVICE_TYPE, "seneca Z-D-IN"},
+		}
+		if len(plan.Changes) != len(expected) {
+			t.Fatalf("expected %d changes, got:\n%s", len(expected), plan)
+		}
+		for i, e := range expected {
+			change := plan.Changes[i]
+			if change.Action != e.action || change.Kind != e.kind || change.Name != e.name {
+				t.Fatalf("expected change %d to be %s %s %q, got %s %s %q", i, e.action, e.kind, e.name, change.Action, change.Kind, change.Name)
+			}
+		}
+		if !strings.Contains(plan.String(), "allowConcurrent: false => true") {
+			t.Fatalf("expected allowConcurrent diff, got:\n%s", plan)
+		}
+
+		// Plans saved as JSON are applied as they are
+		b, err := json.Marshal(plan)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var saved goplatform.Plan
+		if err := json.Unmarshal(b, &saved); err != nil {
+			t.Fatal(err)
+		}
+
+		result, err := project.Apply(context.Background(), saved)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Applied) != len(expected) {
+			t.Fatalf("expected %d applied changes, got %d", len(expected), len(result.Applied))
+		}
+	})
+
+	t.Run("Plan without Prune", func(t *testing.T) {
+		platform := goplatform.New(goplatform.Config{
+			Uri:    API_URI,
+			ApiKey: API_KEY,
+		})
+		project := platform.Project(PROJECT_ID)
+
+		state, err := goplatform.ParseProjectState([]byte("rules:\n  - name: other\n    status: enabled\n    mode: edge\n    triggers:\n      - type: topic\n        topic: my-topic\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, options := range []goplatform.PlanOptions{{}, {Prune: true}} {
+			plan, err := project.Plan(context.Background(), state, options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, change := range plan.Changes {
+				if change.Kind != goplatform.RESOURCE_RULE {
+					t.Fatalf("expected only rules to be planned, got:\n%s", plan)
+				}
+				if change.Action == goplatform.PLAN_DELETE && !options.Prune {
+					t.Fatalf("expected no deletes without Prune, got:\n%s", plan)
+				}
+			}
+		}
+	})
+
 	t.Run("Run Command", func(t *testing.T) {
 		ctx := context.Background()
 
@@ -567,3 +926,160 @@ func TestUnit(t *testing.T) {
 		}
 	})
 }
+
+func TestPlanPartial(t *testing.T) {
+	gock.Off()
+	defer gock.Off()
+
+	rule := map[string]any{
+		"uuid":            RULE_ID,
+		"projectId":       PROJECT_ID,
+		"name":            "test",
+		"status":          "enabled",
+		"mode":            "edge",
+		"allowConcurrent": true,
+		"triggers":        []map[string]any{{"type": "topic", "topic": "my-topic"}},
+	}
+
+	mockResources := func(rules ...map[string]any) {
+		for _, path := range []string{"devicetypes", "nodes", "devices"} {
+			gock.New(API_URI).
+				Get("/projects/" + PROJECT_ID + "/" + path + "$").
+				Reply(200).
+				JSON(map[string]any{"status": true, "data": []any{}})
+		}
+		gock.New(API_URI).
+			Get("/projects/" + PROJECT_ID + "/rules$").
+			Reply(200).
+			JSON(map[string]any{"status": true, "data": rules})
+	}
+
+	platform := goplatform.New(goplatform.Config{
+		Uri:    API_URI,
+		ApiKey: API_KEY,
+	})
+	project := platform.Project(PROJECT_ID)
+
+	t.Run("Omitted fields", func(t *testing.T) {
+		mockResources(rule)
+
+		state, err := goplatform.ParseProjectState([]byte("rules:\n  - name: test\n    status: enabled\n    mode: edge\n    triggers:\n      - type: topic\n        topic: my-topic\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		plan, err := project.Plan(context.Background(), state)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !plan.Empty() {
+			t.Fatalf("expected omitted allowConcurrent to be ignored, got:\n%s", plan)
+		}
+	})
+
+	t.Run("Explicit false", func(t *testing.T) {
+		mockResources(rule)
+
+		state, err := goplatform.ParseProjectState([]byte("rules:\n  - name: test\n    status: enabled\n    mode: edge\n    allowConcurrent: false\n    triggers:\n      - type: topic\n        topic: my-topic\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		plan, err := project.Plan(context.Background(), state)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(plan.Changes) != 1 || len(plan.Changes[0].Diffs) != 1 || plan.Changes[0].Diffs[0].Path != "allowConcurrent" {
+			t.Fatalf("expected an allowConcurrent diff, got:\n%s", plan)
+		}
+		if plan.Changes[0].Document["allowConcurrent"] != false {
+			t.Fatalf("expected allowConcurrent to be sent as false, got %v", plan.Changes[0].Document["allowConcurrent"])
+		}
+	})
+
+	t.Run("Duplicate names", func(t *testing.T) {
+		other := maps.Clone(rule)
+		other["uuid"] = "my-other-rule-id"
+		mockResources(rule, other)
+
+		state := goplatform.ProjectState{Rules: []goplatform.Rule{{Name: "test"}}}
+		if _, err := project.Plan(context.Background(), state); err == nil || !strings.Contains(err.Error(), "duplicate") {
+			t.Fatalf("expected duplicate name error, got %v", err)
+		}
+	})
+}
+
+func TestSharedClient(t *testing.T) {
+	// A real server, to observe the connections opened by the client
+	gock.Off()
+
+	var mu sync.Mutex
+	connections := 0
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Authorization") != "apiKey "+API_KEY {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch req.URL.Path {
+		case "/projects/" + PROJECT_ID + "/":
+			fmt.Fprintf(w, `{"status":true,"data":{"uuid":%q}}`, PROJECT_ID)
+		case "/projects/" + PROJECT_ID + "/devices":
+			fmt.Fprintf(w, `{"status":true,"data":[{"uuid":%q,"projectId":%q}]}`, DEVICE_ID, PROJECT_ID)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	ts.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			mu.Lock()
+			connections++
+			mu.Unlock()
+		}
+	}
+	ts.Start()
+	defer ts.Close()
+
+	platform := goplatform.New(goplatform.Config{
+		Uri:    ts.URL,
+		ApiKey: API_KEY,
+		// Gives the client a transport to reach the server past gock
+		SkipVerify: true,
+	})
+
+	t.Run("Copies share the client", func(t *testing.T) {
+		copied := platform
+		if copied.Client != platform.Client {
+			t.Fatal("expected copies of a Platform to share the Client")
+		}
+	})
+
+	t.Run("Resources share the connection", func(t *testing.T) {
+		ctx := context.Background()
+
+		project, err := platform.GetProject(ctx, PROJECT_ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range []goplatform.Project{project, platform.Project(PROJECT_ID)} {
+			if _, err := p.GetDevices(ctx); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if connections != 1 {
+			t.Fatalf("expected 1 connection got %d", connections)
+		}
+	})
+
+	t.Run("Zero Platform", func(t *testing.T) {
+		var zero goplatform.Platform
+		if _, err := zero.GetProjects(context.Background()); err == nil {
+			t.Fatal("expected error from a zero Platform")
+		}
+		if _, err := (goplatform.Project{}).GetDevices(context.Background()); err == nil {
+			t.Fatal("expected error from a zero Project")
+		}
+	})
+}
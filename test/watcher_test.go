@@ -0,0 +1,121 @@
+package goplatform_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ApioIoT/goplatform/v2"
+	"github.com/h2non/gock"
+)
+
+func watchDevices(devices ...map[string]any) map[string]any {
+	return map[string]any{"status": true, "data": devices}
+}
+
+func TestWatcher(t *testing.T) {
+	defer gock.Off()
+
+	devicesUrl := "/projects/" + PROJECT_ID + "/devices"
+
+	gock.New(API_URI).
+		Get("/projects/" + PROJECT_ID + "/").
+		Reply(200).
+		JSON(map[string]any{"status": true, "data": map[string]any{"uuid": PROJECT_ID}})
+
+	gock.New(API_URI).
+		Get(devicesUrl).
+		Reply(200).
+		JSON(watchDevices(
+			map[string]any{"uuid": "a", "name": "first", "state": map[string]any{"t": 1}, "updatedAt": "2025-01-01T00:00:00Z"},
+			map[string]any{"uuid": "b", "name": "second", "updatedAt": "2025-01-01T00:00:00Z"},
+		))
+
+	gock.New(API_URI).
+		Get(devicesUrl).
+		Reply(200).
+		JSON(watchDevices(
+			map[string]any{"uuid": "a", "name": "first", "state": map[string]any{"t": 2}, "updatedAt": "2025-01-02T00:00:00Z"},
+			map[string]any{"uuid": "c", "name": "third", "updatedAt": "2025-01-02T00:00:00Z"},
+		))
+
+	gock.New(API_URI).
+		Get(devicesUrl).
+		Reply(http.StatusServiceUnavailable)
+
+	gock.New(API_URI).
+		Get(devicesUrl).
+		Reply(200).
+		JSON(watchDevices(
+			map[string]any{"uuid": "a", "name": "renamed", "state": map[string]any{"t": 2}, "updatedAt": "2025-01-03T00:00:00Z"},
+		))
+
+	gock.New(API_URI).
+		Get(devicesUrl).
+		Persist().
+		Reply(http.StatusForbidden)
+
+	platform := goplatform.New(goplatform.Config{
+		Uri:    API_URI,
+		ApiKey: API_KEY,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	project, err := platform.GetProject(ctx, PROJECT_ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var errs []error
+	watcher := goplatform.NewDeviceWatcher(project, goplatform.WatchOptions{
+		Interval: time.Millisecond,
+		OnError:  func(err error) { errs = append(errs, err) },
+	})
+
+	var events []goplatform.WatchEvent[goplatform.Device]
+	for event := range watcher.Watch(ctx) {
+		events = append(events, event)
+	}
+
+	expected := []struct {
+		kind goplatform.WatchEventType
+		uuid string
+	}{
+		{goplatform.WATCH_ADDED, "a"},
+		{goplatform.WATCH_ADDED, "b"},
+		{goplatform.WATCH_UPDATED, "a"},
+		{goplatform.WATCH_ADDED, "c"},
+		{goplatform.WATCH_REMOVED, "b"},
+		{goplatform.WATCH_UPDATED, "a"},
+		{goplatform.WATCH_REMOVED, "c"},
+	}
+
+	if len(events) != len(expected) {
+		t.Fatalf("expected %d events got %d: %+v", len(expected), len(events), events)
+	}
+	for i, e := range expected {
+		if events[i].Type != e.kind || events[i].Uuid != e.uuid {
+			t.Fatalf("expected event %d %s %s got %s %s", i, e.kind, e.uuid, events[i].Type, events[i].Uuid)
+		}
+	}
+
+	if diffs := events[2].Diffs; len(diffs) != 1 || diffs[0].Path != "state.t" || diffs[0].Old != 1.0 || diffs[0].New != 2.0 {
+		t.Fatalf("expected state.t diff got %+v", diffs)
+	}
+	if diffs := events[5].Diffs; len(diffs) != 1 || diffs[0].Path != "name" || events[5].Previous.Name != "first" {
+		t.Fatalf("expected name diff got %+v", diffs)
+	}
+	if events[4].Object.Name != "second" {
+		t.Fatalf("expected removed device second got %s", events[4].Object.Name)
+	}
+
+	if ctx.Err() != nil {
+		t.Fatal("expected watch to stop on forbidden")
+	}
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors got %d", len(errs))
+	}
+}
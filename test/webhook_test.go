@@ -0,0 +1,155 @@
+package goplatform_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ApioIoT/goplatform/v2"
+)
+
+func TestWebhookReceiver(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	receiver := goplatform.NewWebhookReceiver(goplatform.WebhookOptions{
+		Secret: "my-secret",
+		Now:    func() time.Time { return now },
+	})
+
+	type alarm struct {
+		Temperature float64 `json:"temperature"`
+	}
+
+	var received []goplatform.WebhookRequest
+	var payload alarm
+	fail := false
+	receiver.Handle(RULE_ID, func(ctx context.Context, req goplatform.WebhookRequest) error {
+		if fail {
+			return errors.New("handler failure")
+		}
+		received = append(received, req)
+		return req.Decode(&payload)
+	})
+
+	body := `{"ruleId":"` + RULE_ID + `","projectId":"` + PROJECT_ID + `","deviceId":"` + DEVICE_ID + `","payload":{"temperature":31.5}}`
+
+	send := func(body, delivery string, headers map[string]string) int {
+		req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+		if delivery != "" {
+			req.Header.Set(goplatform.WEBHOOK_HEADER_DELIVERY, delivery)
+		}
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		receiver.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	signed := func(body string, at time.Time) map[string]string {
+		return map[string]string{
+			goplatform.WEBHOOK_HEADER_TIMESTAMP: strconv.FormatInt(at.Unix(), 10),
+			goplatform.WEBHOOK_HEADER_SIGNATURE: goplatform.SignWebhook("my-secret", at, []byte(body)),
+		}
+	}
+
+	t.Run("Signed", func(t *testing.T) {
+		if code := send(body, "d1", signed(body, now)); code != http.StatusNoContent {
+			t.Fatalf("expected 204 got %d", code)
+		}
+		if len(received) != 1 {
+			t.Fatalf("expected 1 call got %d", len(received))
+		}
+		req := received[0]
+		if req.RuleId != RULE_ID || req.ProjectId != PROJECT_ID || req.DeviceId != DEVICE_ID || req.DeliveryId != "d1" {
+			t.Fatalf("unexpected request %+v", req)
+		}
+		if payload.Temperature != 31.5 {
+			t.Fatalf("expected temperature 31.5 got %v", payload.Temperature)
+		}
+	})
+
+	t.Run("Replay", func(t *testing.T) {
+		if code := send(body, "d1", signed(body, now)); code != http.StatusConflict {
+			t.Fatalf("expected 409 got %d", code)
+		}
+		// The delivery id is not signed, the signature identifies the request
+		if code := send(body, "d1-replayed", signed(body, now)); code != http.StatusConflict {
+			t.Fatalf("expected 409 with another delivery id got %d", code)
+		}
+		if code := send(body, "", signed(body, now)); code != http.StatusConflict {
+			t.Fatalf("expected 409 without delivery id got %d", code)
+		}
+		if len(received) != 1 {
+			t.Fatalf("expected 1 call got %d", len(received))
+		}
+	})
+
+	t.Run("Invalid signature", func(t *testing.T) {
+		headers := signed(body, now)
+		headers[goplatform.WEBHOOK_HEADER_SIGNATURE] = goplatform.SignWebhook("other-secret", now, []byte(body))
+		if code := send(body, "d2", headers); code != http.StatusUnauthorized {
+			t.Fatalf("expected 401 got %d", code)
+		}
+		if code := send(body, "d2", signed(body, now.Add(-time.Hour))); code != http.StatusUnauthorized {
+			t.Fatalf("expected 401 on stale timestamp got %d", code)
+		}
+		if code := send(body, "d2", nil); code != http.StatusUnauthorized {
+			t.Fatalf("expected 401 without signature got %d", code)
+		}
+	})
+
+	t.Run("Shared secret", func(t *testing.T) {
+		if code := send(body, "d3", map[string]string{goplatform.WEBHOOK_HEADER_SECRET: "my-secret"}); code != http.StatusNoContent {
+			t.Fatalf("expected 204 got %d", code)
+		}
+	})
+
+	t.Run("Handler error", func(t *testing.T) {
+		at := now.Add(time.Second)
+		fail = true
+		if code := send(body, "d4", signed(body, at)); code != http.StatusInternalServerError {
+			t.Fatalf("expected 500 got %d", code)
+		}
+		fail = false
+		if code := send(body, "d4", signed(body, at)); code != http.StatusNoContent {
+			t.Fatalf("expected retry to succeed got %d", code)
+		}
+	})
+
+	t.Run("Unknown rule", func(t *testing.T) {
+		other := `{"ruleId":"other-rule","payload":{}}`
+		if code := send(other, "d5", signed(other, now)); code != http.StatusNotFound {
+			t.Fatalf("expected 404 got %d", code)
+		}
+	})
+
+	t.Run("Raw payload", func(t *testing.T) {
+		raw := `{"ruleId":"` + RULE_ID + `","temperature":40}`
+		if code := send(raw, "d6", signed(raw, now)); code != http.StatusNoContent {
+			t.Fatalf("expected 204 got %d", code)
+		}
+		if payload.Temperature != 40 {
+			t.Fatalf("expected temperature 40 got %v", payload.Temperature)
+		}
+	})
+
+	t.Run("Future timestamp", func(t *testing.T) {
+		at := now.Add(4 * time.Minute)
+		if code := send(body, "d7", signed(body, at)); code != http.StatusNoContent {
+			t.Fatalf("expected 204 got %d", code)
+		}
+
+		// The timestamp is still accepted after the tolerance has elapsed
+		// since the request was received
+		now = now.Add(6 * time.Minute)
+		if code := send(body, "d7-replayed", signed(body, at)); code != http.StatusConflict {
+			t.Fatalf("expected 409 on future dated replay got %d", code)
+		}
+	})
+}
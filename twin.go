@@ -0,0 +1,199 @@
+package goplatform
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+)
+
+const (
+	TWIN_COMMAND_MODBUS_WRITE = "modbusWrite"
+	TWIN_COMMAND_KNX_WRITE    = "knxWrite"
+)
+
+// DeviceTwin is the reported state of a device next to its desired state.
+// Delta holds the desired properties whose reported value differs.
+type DeviceTwin struct {
+	Reported map[string]any `json:"reported"`
+	Desired  map[string]any `json:"desired"`
+	Delta    map[string]any `json:"delta"`
+}
+
+// TwinSkip is a delta property the reconciler cannot write.
+type TwinSkip struct {
+	Property string
+	Err      error
+}
+
+type ReconcileOptions struct {
+	// DryRun builds the commands without sending them.
+	DryRun bool
+}
+
+// TwinReconciliation is the outcome of Device.Reconcile.
+type TwinReconciliation struct {
+	Commands []Command
+	Skipped  []TwinSkip
+}
+
+// Twin returns the reported, desired and delta state of the device. Values
+// are compared after coercion to the device type property types, so "1" and
+// 1 match an integer property.
+func (d Device) Twin() DeviceTwin {
+	twin := DeviceTwin{
+		Reported: maps.Clone(d.State),
+		Desired:  maps.Clone(d.DesiredState),
+		Delta:    map[string]any{},
+	}
+	if twin.Reported == nil {
+		twin.Reported = map[string]any{}
+	}
+	if twin.Desired == nil {
+		twin.Desired = map[string]any{}
+	}
+
+	for name, desired := range twin.Desired {
+		reported, ok := d.StateValue(name)
+		if ok && sameStateValue(d.coerce(name, desired), reported) {
+			continue
+		}
+		twin.Delta[name] = desired
+	}
+
+	return twin
+}
+
+// SetDesiredState patches the desired state of the device with desired,
+// sending only the given properties so that other fields and concurrent
+// changes to other properties are not overwritten. Properties set to nil are
+// removed from the desired state.
+func (d Device) SetDesiredState(ctx context.Context, desired map[string]any) (Device, error) {
+	project, err := projectOf(d.platformRef, "device", d.Uuid, d.ProjectID)
+	if err != nil {
+		return d, err
+	}
+
+	body := map[string]any{"desiredState": desired}
+	res, err := sendResource[Device](ctx, project.platformRef, httpPatch, body, "projects", project.Uuid, "devices", d.Uuid)
+	if err != nil {
+		return d, err
+	}
+
+	res.platformRef = project.platformRef
+
+	return res, nil
+}
+
+// Reconcile converts the delta of the device twin into write commands for
+// the writable properties of its device type, Modbus registers with Write
+// set and KNX properties with a send datapoint type, and sends them in
+// property order. Properties that cannot be written are reported in Skipped.
+func (d Device) Reconcile(ctx context.Context, options ...ReconcileOptions) (TwinReconciliation, error) {
+	var res TwinReconciliation
+
+	var _options ReconcileOptions
+	if len(options) > 0 {
+		_options = options[0]
+	}
+
+	delta := d.Twin().Delta
+	if len(delta) == 0 {
+		return res, nil
+	}
+
+	deviceType, err := d.Type(ctx)
+	if err != nil {
+		return res, err
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(delta)) {
+		command, params, err := twinWriteCommand(deviceType, name, d.coerce(name, delta[name]))
+		if err != nil {
+			res.Skipped = append(res.Skipped, TwinSkip{Property: name, Err: err})
+			continue
+		}
+
+		var cmd Command
+		if _options.DryRun {
+			cmd = d.commandRequest(d.ProjectID, command, params, SendCommandOptions{}).MakeCommand()
+		} else {
+			cmd, err = d.SendCommand(ctx, command, params)
+			if err != nil {
+				return res, err
+			}
+		}
+		res.Commands = append(res.Commands, cmd)
+	}
+
+	return res, nil
+}
+
+// twinWriteCommand returns the command writing value to property.
+func twinWriteCommand(deviceType DeviceType, property string, value any) (string, CommandParameter, error) {
+	var errs []error
+
+	if deviceType.Protocols != nil && deviceType.Protocols.Modbus != nil {
+		req, err := EncodeRegisterWrite(*deviceType.Protocols.Modbus, property, value)
+		if err == nil {
+			return TWIN_COMMAND_MODBUS_WRITE, CommandParameter{
+				"property": property,
+				"value":    value,
+				"function": req.Function,
+				"address":  req.Address,
+				"words":    req.Words,
+			}, nil
+		}
+		errs = append(errs, err)
+	}
+
+	if deviceType.Protocols != nil && deviceType.Protocols.Knx != nil {
+		if prop, ok := deviceType.Protocols.Knx.Properties[property]; ok && prop.SendDPT != "" {
+			address, data, err := deviceType.Protocols.Knx.EncodeProperty(property, value)
+			if err == nil {
+				return TWIN_COMMAND_KNX_WRITE, CommandParameter{
+					"property": property,
+					"value":    value,
+					"address":  address.String(),
+					"data":     hex.EncodeToString(data),
+				}, nil
+			}
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return "", nil, errs[0]
+	}
+	return "", nil, fmt.Errorf("goplatform: twin: property %q is not writable", property)
+}
+
+func (d Device) coerce(name string, value any) any {
+	if d.DeviceType == nil {
+		return value
+	}
+	if coerced, err := d.DeviceType.CoerceProperty(name, value); err == nil {
+		return coerced
+	}
+	return value
+}
+
+// sameStateValue compares state values, treating numbers of different Go
+// types as equal when their values are.
+func sameStateValue(a, b any) bool {
+	if reflect.DeepEqual(a, b) {
+		return true
+	}
+
+	_, aString := a.(string)
+	_, bString := b.(string)
+	if aString || bString {
+		return false
+	}
+
+	fa, aok := toFloat64(a)
+	fb, bok := toFloat64(b)
+	return aok && bok && fa == fb
+}
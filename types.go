@@ -52,16 +52,16 @@ type Node struct {
 		BackoffFactor    float64 `json:"backoffFactor"`
 		BackoffTimeLimit int     `json:"backoffTimeLimit"`
 	} `json:"retry,omitempty"`
-	ConnectivityStatus  string    `json:"connectivityStatus"`
-	LastConnectionAt    string    `json:"lastConnectionAt"`
-	LastCommunicationAt string    `json:"lastCommunicationAt"`
-	LastDisconnectionAt string    `json:"lastDisconnectionAt"`
-	Description         string    `json:"description,omitempty"`
-	Tags                []string  `json:"tags"`
-	Rules               []string  `json:"rules,omitempty"`
-	CreatedAt           time.Time `json:"createdAt,omitempty"`
-	UpdatedAt           time.Time `json:"updatedAt,omitempty"`
-	platformRef         *Platform `json:"-"`
+	ConnectivityStatus  ConnectivityStatus `json:"connectivityStatus"`
+	LastConnectionAt    string             `json:"lastConnectionAt"`
+	LastCommunicationAt string             `json:"lastCommunicationAt"`
+	LastDisconnectionAt string             `json:"lastDisconnectionAt"`
+	Description         string             `json:"description,omitempty"`
+	Tags                []string           `json:"tags"`
+	Rules               []string           `json:"rules,omitempty"`
+	CreatedAt           time.Time          `json:"createdAt,omitempty"`
+	UpdatedAt           time.Time          `json:"updatedAt,omitempty"`
+	platformRef         *Client            `json:"-"`
 }
 
 type Device struct {
@@ -79,17 +79,18 @@ type Device struct {
 	Description         string               `json:"description,omitempty"`
 	DeviceType          *DeviceType          `json:"deviceType,omitempty"`
 	Metadata            map[string]any       `json:"metadata"`
-	State               any                  `json:"state"`
-	StateUpdatedAt      any                  `json:"stateUpdatedAt"`
+	State               map[string]any       `json:"state"`
+	StateUpdatedAt      map[string]time.Time `json:"stateUpdatedAt"`
+	DesiredState        map[string]any       `json:"desiredState,omitempty"`
 	LastActivityAt      string               `json:"lastActivityAt"`
 	LastCommunicationAt string               `json:"lastCommunicationAt"`
-	ConnectivityStatus  string               `json:"connectivityStatus"`
+	ConnectivityStatus  ConnectivityStatus   `json:"connectivityStatus"`
 	LastConnectionAt    string               `json:"lastConnectionAt"`
 	LastDisconnectionAt string               `json:"lastDisconnectionAt"`
 	Tags                []string             `json:"tags"`
 	CreatedAt           time.Time            `json:"createdAt,omitempty"`
 	UpdatedAt           time.Time            `json:"updatedAt,omitempty"`
-	platformRef         *Platform            `json:"-"`
+	platformRef         *Client              `json:"-"`
 }
 
 type DeviceTypeModbusProtocol struct {
@@ -98,27 +99,31 @@ type DeviceTypeModbusProtocol struct {
 }
 
 type DeviceTypeModbusRegister struct {
-	Register            uint16 `json:"register"`
-	Read                bool   `json:"read"`
-	ModbusFunctionRead  int    `json:"modbusFunctionRead"`
-	Write               bool   `json:"write"`
-	ModbusFunctionWrite int    `json:"modbusFunctionWrite,omitempty"`
-	Words               byte   `json:"words"`
-	BitwiseReading      bool   `json:"bitwiseReading"`
-	Properties          []struct {
-		Index int    `json:"index"`
-		Name  string `json:"name"`
-	} `json:"properties"`
-	ScaleFactor *float64 `json:"scaleFactor,omitempty"`
-	Type        string   `json:"type"`
+	Register            uint16                     `json:"register"`
+	Read                bool                       `json:"read"`
+	ModbusFunctionRead  int                        `json:"modbusFunctionRead"`
+	Write               bool                       `json:"write"`
+	ModbusFunctionWrite int                        `json:"modbusFunctionWrite,omitempty"`
+	Words               byte                       `json:"words"`
+	BitwiseReading      bool                       `json:"bitwiseReading"`
+	Properties          []DeviceTypeModbusProperty `json:"properties"`
+	ScaleFactor         *float64                   `json:"scaleFactor,omitempty"`
+	Type                string                     `json:"type"`
+}
+
+type DeviceTypeModbusProperty struct {
+	Index int    `json:"index"`
+	Name  string `json:"name"`
 }
 
 type DeviceTypeKnxProtocol struct {
-	Properties map[string]struct {
-		Address    string `json:"address"`
-		SendDPT    string `json:"sendDPT"`
-		ReceiveDPT string `json:"receiveDPT"`
-	} `json:"properties"`
+	Properties map[string]DeviceTypeKnxProperty `json:"properties"`
+}
+
+type DeviceTypeKnxProperty struct {
+	Address    string `json:"address"`
+	SendDPT    string `json:"sendDPT"`
+	ReceiveDPT string `json:"receiveDPT"`
 }
 
 type DeviceType struct {
@@ -138,13 +143,39 @@ type DeviceType struct {
 		Modbus *DeviceTypeModbusProtocol `json:"modbus,omitempty"`
 		Knx    *DeviceTypeKnxProtocol    `json:"knx,omitempty"`
 	} `json:"protocols,omitempty"`
-	Metadata    map[string]any `json:"metadata"`
-	Commands    any            `json:"commands"`
-	Events      any            `json:"events"`
-	Properties  any            `json:"properties"`
-	CreatedAt   time.Time      `json:"createdAt,omitempty"`
-	UpdatedAt   time.Time      `json:"updatedAt,omitempty"`
-	platformRef *Platform      `json:"-"`
+	Metadata    map[string]any                `json:"metadata"`
+	Commands    map[string]DeviceTypeCommand  `json:"commands"`
+	Events      any                           `json:"events"`
+	Properties  map[string]DeviceTypeProperty `json:"properties"`
+	CreatedAt   time.Time                     `json:"createdAt,omitempty"`
+	UpdatedAt   time.Time                     `json:"updatedAt,omitempty"`
+	platformRef *Client                       `json:"-"`
+	// projectRef is the project the device type was fetched through, which
+	// differs from ProjectID for shared device types.
+	projectRef string
+}
+
+type DeviceTypeCommand struct {
+	Description string                                `json:"description,omitempty"`
+	DisplayName string                                `json:"displayName,omitempty"`
+	Parameters  map[string]DeviceTypeCommandParameter `json:"parameters,omitempty"`
+}
+
+type DeviceTypeCommandParameter struct {
+	Description string `json:"description,omitempty"`
+	Type        string `json:"type,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+type DeviceTypeProperty struct {
+	Uom          string   `json:"uom,omitempty"`
+	Description  string   `json:"description,omitempty"`
+	DisplayName  string   `json:"displayName,omitempty"`
+	Type         string   `json:"type,omitempty"`
+	Validation   any      `json:"validation,omitempty"`
+	Aggregations []string `json:"aggregations,omitempty"`
+	// Extras holds the fields not listed above, sent back unchanged.
+	Extras map[string]any `json:"-"`
 }
 
 type Event struct {
@@ -158,6 +189,30 @@ type Event struct {
 	Data        any            `json:"data,omitempty"`
 }
 
+const (
+	RULE_ACTION_WASM    = "wasm"
+	RULE_ACTION_LOG     = "log"
+	RULE_ACTION_WEBHOOK = "webhook"
+	RULE_ACTION_COMMAND = "command"
+	RULE_ACTION_DELAY   = "delay"
+)
+
+const (
+	RULE_TRIGGER_TELEMETRY = "telemetry"
+	RULE_TRIGGER_CRON      = "cron"
+	RULE_TRIGGER_TOPIC     = "topic"
+)
+
+const (
+	RULE_STATUS_ENABLED  = "enabled"
+	RULE_STATUS_DISABLED = "disabled"
+)
+
+const (
+	RULE_MODE_EDGE  = "edge"
+	RULE_MODE_CLOUD = "cloud"
+)
+
 type RuleAction struct {
 	Type string `json:"type"`
 	// Type wasm
@@ -206,7 +261,7 @@ type Rule struct {
 	Actions         []RuleAction   `json:"actions"`
 	ElseActions     []RuleAction   `json:"elseActions"`
 	Condition       *RuleCondition `json:"condition,omitempty"`
-	platformRef     *Platform      `json:"-"`
+	platformRef     *Client        `json:"-"`
 }
 
 type Measure struct {
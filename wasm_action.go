@@ -0,0 +1,200 @@
+package goplatform
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+)
+
+const (
+	wasmSectionExport = 7
+	wasmExportFunc    = 0
+)
+
+var wasmMagic = []byte{0x00, 'a', 's', 'm'}
+
+// wasmModule is a module decoded from binary and verified against hash.
+type wasmModule struct {
+	binary, hash string
+	module       []byte
+}
+
+// WasmActionOptions are the checks made on a module packaged as an action.
+type WasmActionOptions struct {
+	// Exports are the functions the module must export.
+	Exports []string
+}
+
+// WasmActionFromFile returns a wasm action running the compiled module at
+// path. See WasmActionFromModule.
+func WasmActionFromFile(label, language, path string, options ...WasmActionOptions) (RuleAction, error) {
+	module, err := os.ReadFile(path)
+	if err != nil {
+		return RuleAction{}, err
+	}
+
+	return WasmActionFromModule(label, language, module, options...)
+}
+
+// WasmActionFromModule returns a wasm action running module, a compiled
+// WebAssembly binary. The module header and the required exports are checked,
+// Binary is the base64 encoded module and Hash its hex encoded SHA-256.
+func WasmActionFromModule(label, language string, module []byte, options ...WasmActionOptions) (RuleAction, error) {
+	_options := WasmActionOptions{}
+	if len(options) > 0 {
+		_options = options[0]
+	}
+
+	exports, err := wasmExports(module)
+	if err != nil {
+		return RuleAction{}, fmt.Errorf("goplatform: wasm module: %w", err)
+	}
+
+	var missing []error
+	for _, name := range _options.Exports {
+		if !slices.Contains(exports, name) {
+			missing = append(missing, fmt.Errorf("missing export %s", name))
+		}
+	}
+	if len(missing) > 0 {
+		return RuleAction{}, fmt.Errorf("goplatform: wasm module: %w", errors.Join(missing...))
+	}
+
+	return WasmRuleAction{
+		Label:    label,
+		Language: language,
+		Binary:   base64.StdEncoding.EncodeToString(module),
+		Hash:     wasmHash(module),
+	}.Action(), nil
+}
+
+// Module decodes Binary and checks it against Hash, when set. The module
+// verified by Variant is returned without decoding it again.
+func (a WasmRuleAction) Module() ([]byte, error) {
+	if a.verified != nil && a.verified.binary == a.Binary && a.verified.hash == a.Hash {
+		return a.verified.module, nil
+	}
+
+	module, err := decodeWasmBinary(a.Binary, a.Hash)
+	if err != nil {
+		return nil, fmt.Errorf("goplatform: wasm action: %w", err)
+	}
+
+	return module, nil
+}
+
+func decodeWasmBinary(b64, hash string) ([]byte, error) {
+	module, err := base64.StdEncoding.DecodeString(b64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid binary: %w", err)
+	}
+
+	if hash != "" {
+		expected, err := hex.DecodeString(hash)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hash: %w", err)
+		}
+		if sum := sha256.Sum256(module); !bytes.Equal(sum[:], expected) {
+			return nil, fmt.Errorf("binary hash %x does not match hash %s", sum, hash)
+		}
+	}
+
+	return module, nil
+}
+
+func wasmHash(module []byte) string {
+	sum := sha256.Sum256(module)
+	return hex.EncodeToString(sum[:])
+}
+
+// wasmExports checks the module header and section layout and returns the
+// names of the exported functions.
+func wasmExports(module []byte) ([]string, error) {
+	if len(module) < 8 || !bytes.Equal(module[:4], wasmMagic) {
+		return nil, errors.New("not a WebAssembly binary")
+	}
+	if version := binary.LittleEndian.Uint32(module[4:8]); version != 1 {
+		return nil, fmt.Errorf("unsupported version %d", version)
+	}
+
+	var exports []string
+	r := wasmReader{b: module[8:]}
+	for r.len() > 0 {
+		id := r.byte()
+		size := r.uleb()
+		if r.err != nil || size > uint64(r.len()) {
+			return nil, errors.New("truncated section")
+		}
+		section := wasmReader{b: r.next(int(size))}
+
+		if id != wasmSectionExport {
+			continue
+		}
+
+		count := section.uleb()
+		for i := uint64(0); i < count && section.err == nil; i++ {
+			name := string(section.next(int(section.uleb())))
+			kind := section.byte()
+			section.uleb()
+			if kind == wasmExportFunc {
+				exports = append(exports, name)
+			}
+		}
+		if section.err != nil {
+			return nil, errors.New("malformed export section")
+		}
+	}
+
+	return exports, nil
+}
+
+// wasmReader reads the primitive values of the binary format, recording the
+// first out of bounds read in err.
+type wasmReader struct {
+	b   []byte
+	err error
+}
+
+func (r *wasmReader) len() int {
+	return len(r.b)
+}
+
+func (r *wasmReader) next(n int) []byte {
+	if r.err != nil || n < 0 || n > len(r.b) {
+		r.err = errors.New("unexpected end of module")
+		return nil
+	}
+	b := r.b[:n]
+	r.b = r.b[n:]
+	return b
+}
+
+func (r *wasmReader) byte() byte {
+	b := r.next(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (r *wasmReader) uleb() uint64 {
+	var v uint64
+	for shift := 0; shift < 64; shift += 7 {
+		b := r.byte()
+		if r.err != nil {
+			return 0
+		}
+		v |= uint64(b&0x7f) << shift
+		if b&0x80 == 0 {
+			return v
+		}
+	}
+	r.err = errors.New("malformed integer")
+	return 0
+}
@@ -0,0 +1,204 @@
+package goplatform
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"time"
+)
+
+type WatchEventType string
+
+const (
+	WATCH_ADDED   WatchEventType = "added"
+	WATCH_UPDATED WatchEventType = "updated"
+	WATCH_REMOVED WatchEventType = "removed"
+)
+
+// WatchEvent is a change of a node or device between two polls. Object is
+// the current resource, or the last seen one when removed; Previous and Diffs
+// are only set on updates.
+type WatchEvent[T any] struct {
+	Type     WatchEventType
+	Uuid     string
+	Object   T
+	Previous T
+	Diffs    []PlanDiff
+}
+
+type WatchOptions struct {
+	// Interval is the initial polling interval, 10s by default. The interval
+	// shrinks to MinInterval when changes are found and grows up to
+	// MaxInterval while nothing changes or polls fail.
+	Interval    time.Duration
+	MinInterval time.Duration
+	MaxInterval time.Duration
+	// BufferSize is the capacity of the event channel, 64 by default.
+	BufferSize int
+	// OnError is called with the errors of failed polls.
+	OnError func(error)
+}
+
+// Watcher polls a list of resources and emits the differences between
+// consecutive snapshots. The first poll reports every resource as added.
+// A Watcher keeps a single snapshot and is not safe for concurrent use: use
+// either Poll or one Watch at a time.
+type Watcher[T any] struct {
+	list     func(context.Context) ([]T, error)
+	key      func(T) (string, time.Time)
+	options  WatchOptions
+	snapshot map[string]T
+}
+
+// NewNodeWatcher returns a watcher of the project nodes.
+func NewNodeWatcher(project Project, options ...WatchOptions) *Watcher[Node] {
+	return newWatcher(project.GetNodes, func(n Node) (string, time.Time) {
+		return n.Uuid, n.UpdatedAt
+	}, options...)
+}
+
+// NewDeviceWatcher returns a watcher of the project devices.
+func NewDeviceWatcher(project Project, options ...WatchOptions) *Watcher[Device] {
+	return newWatcher(project.GetDevices, func(d Device) (string, time.Time) {
+		return d.Uuid, d.UpdatedAt
+	}, options...)
+}
+
+func newWatcher[T any](list func(context.Context) ([]T, error), key func(T) (string, time.Time), options ...WatchOptions) *Watcher[T] {
+	var _options WatchOptions
+	if len(options) > 0 {
+		_options = options[0]
+	}
+
+	if _options.Interval <= 0 {
+		_options.Interval = 10 * time.Second
+	}
+	if _options.MinInterval <= 0 || _options.MinInterval > _options.Interval {
+		_options.MinInterval = _options.Interval
+	}
+	if _options.MaxInterval < _options.Interval {
+		_options.MaxInterval = 6 * _options.Interval
+	}
+	if _options.BufferSize <= 0 {
+		_options.BufferSize = 64
+	}
+
+	return &Watcher[T]{
+		list:    list,
+		key:     key,
+		options: _options,
+	}
+}
+
+// Poll lists the resources once and returns the changes since the previous
+// poll. Resources are considered updated when their UpdatedAt changes.
+func (w *Watcher[T]) Poll(ctx context.Context) ([]WatchEvent[T], error) {
+	items, err := w.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []WatchEvent[T]
+	current := make(map[string]T, len(items))
+
+	for _, item := range items {
+		uuid, updatedAt := w.key(item)
+		current[uuid] = item
+
+		prev, ok := w.snapshot[uuid]
+		if !ok {
+			events = append(events, WatchEvent[T]{Type: WATCH_ADDED, Uuid: uuid, Object: item})
+			continue
+		}
+
+		if _, prevUpdatedAt := w.key(prev); prevUpdatedAt.Equal(updatedAt) {
+			continue
+		}
+
+		diffs, err := diffResources(prev, item)
+		if err != nil {
+			return nil, err
+		}
+		if len(diffs) > 0 {
+			events = append(events, WatchEvent[T]{Type: WATCH_UPDATED, Uuid: uuid, Object: item, Previous: prev, Diffs: diffs})
+		}
+	}
+
+	for _, uuid := range slices.Sorted(maps.Keys(w.snapshot)) {
+		if _, ok := current[uuid]; !ok {
+			events = append(events, WatchEvent[T]{Type: WATCH_REMOVED, Uuid: uuid, Object: w.snapshot[uuid]})
+		}
+	}
+
+	w.snapshot = current
+
+	return events, nil
+}
+
+// Watch polls in background until ctx is canceled, then closes the returned
+// channel. Transient errors are retried with a growing interval; the watch
+// stops when the platform rejects the request.
+func (w *Watcher[T]) Watch(ctx context.Context) <-chan WatchEvent[T] {
+	out := make(chan WatchEvent[T], w.options.BufferSize)
+
+	go func() {
+		defer close(out)
+
+		interval := w.options.Interval
+		for {
+			events, err := w.Poll(ctx)
+			switch {
+			case ctx.Err() != nil:
+				return
+			case err != nil:
+				if w.options.OnError != nil {
+					w.options.OnError(err)
+				}
+				var apiErr *APIError
+				if errors.As(err, &apiErr) && !retryableStatus(apiErr.StatusCode) {
+					return
+				}
+				interval = min(2*interval, w.options.MaxInterval)
+			case len(events) > 0:
+				interval = w.options.MinInterval
+			default:
+				interval = min(interval+interval/2, w.options.MaxInterval)
+			}
+
+			for _, event := range events {
+				select {
+				case out <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval):
+			}
+		}
+	}()
+
+	return out
+}
+
+// diffResources returns the fields that differ between two resources, in
+// their JSON form.
+func diffResources(prev, next any) ([]PlanDiff, error) {
+	a, err := toDocument[map[string]any](prev)
+	if err != nil {
+		return nil, err
+	}
+	b, err := toDocument[map[string]any](next)
+	if err != nil {
+		return nil, err
+	}
+
+	delete(a, "updatedAt")
+	delete(b, "updatedAt")
+
+	return diffDocuments("", a, b, false), nil
+}
@@ -0,0 +1,270 @@
+package goplatform
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	WEBHOOK_HEADER_SIGNATURE = "X-Apio-Signature"
+	WEBHOOK_HEADER_TIMESTAMP = "X-Apio-Timestamp"
+	WEBHOOK_HEADER_DELIVERY  = "X-Apio-Delivery"
+	WEBHOOK_HEADER_SECRET    = "X-Apio-Secret"
+)
+
+// WebhookRequest is a call made by a rule webhook action.
+type WebhookRequest struct {
+	// DeliveryId identifies the call, retries keep the same id.
+	DeliveryId string          `json:"-"`
+	RuleId     string          `json:"ruleId"`
+	ProjectId  string          `json:"projectId"`
+	DeviceId   string          `json:"deviceId,omitempty"`
+	Timestamp  time.Time       `json:"timestamp"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+// Decode unmarshals the payload of the webhook action into v.
+func (r WebhookRequest) Decode(v any) error {
+	return json.Unmarshal(r.Payload, v)
+}
+
+type WebhookHandlerFunc func(ctx context.Context, req WebhookRequest) error
+
+type WebhookOptions struct {
+	// Secret enables verification. Requests must carry either an HMAC-SHA256
+	// signature of "timestamp.body" or the secret itself. Signed requests are
+	// rejected when replayed, whatever their delivery id; requests carrying
+	// the secret have no timestamp and are only protected from replays by
+	// their delivery id, so signatures should be preferred.
+	Secret string
+	// Tolerance is the maximum clock skew of a signed request, 5 minutes by
+	// default. Delivery ids are remembered for Tolerance after arrival and
+	// signatures until their timestamp is outside Tolerance, to reject
+	// replays.
+	Tolerance time.Duration
+	// MaxBodySize limits the request body, 1 MiB by default.
+	MaxBodySize int64
+	// Now returns the current time, time.Now by default.
+	Now func() time.Time
+}
+
+// WebhookReceiver is an http.Handler receiving the calls of rule webhook
+// actions and dispatching them to the callbacks registered for the rule.
+type WebhookReceiver struct {
+	options WebhookOptions
+	mu      sync.Mutex
+	// seen maps the keys of the requests received to when they expire.
+	seen     map[string]time.Time
+	handlers map[string][]WebhookHandlerFunc
+}
+
+func NewWebhookReceiver(options ...WebhookOptions) *WebhookReceiver {
+	var _options WebhookOptions
+	if len(options) > 0 {
+		_options = options[0]
+	}
+
+	if _options.Tolerance <= 0 {
+		_options.Tolerance = 5 * time.Minute
+	}
+	if _options.MaxBodySize <= 0 {
+		_options.MaxBodySize = 1 << 20
+	}
+	if _options.Now == nil {
+		_options.Now = time.Now
+	}
+
+	return &WebhookReceiver{
+		options:  _options,
+		seen:     map[string]time.Time{},
+		handlers: map[string][]WebhookHandlerFunc{},
+	}
+}
+
+// Handle registers fn for the calls of ruleId. An empty ruleId receives the
+// calls of every rule.
+func (r *WebhookReceiver) Handle(ruleId string, fn WebhookHandlerFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.handlers[ruleId] = append(r.handlers[ruleId], fn)
+}
+
+func (r *WebhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, req.Body, r.options.MaxBodySize))
+	if err != nil {
+		http.Error(w, "invalid body", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	now := r.options.Now()
+
+	signature, at, err := r.verify(req.Header, body, now)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	webhook, err := parseWebhook(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	webhook.DeliveryId = req.Header.Get(WEBHOOK_HEADER_DELIVERY)
+
+	// The delivery id is not signed, so signed requests are also recognized
+	// by their signature, remembered as long as their timestamp is accepted
+	keys := map[string]time.Time{}
+	if webhook.DeliveryId != "" {
+		keys["delivery:"+webhook.DeliveryId] = now.Add(r.options.Tolerance)
+	}
+	if signature != "" {
+		keys["signature:"+signature] = at.Add(r.options.Tolerance)
+	}
+
+	if !r.remember(keys, now) {
+		http.Error(w, "replayed delivery", http.StatusConflict)
+		return
+	}
+
+	r.mu.Lock()
+	handlers := append(append([]WebhookHandlerFunc{}, r.handlers[webhook.RuleId]...), r.handlers[""]...)
+	r.mu.Unlock()
+
+	if len(handlers) == 0 {
+		r.forget(keys)
+		http.Error(w, "no handler for rule", http.StatusNotFound)
+		return
+	}
+
+	for _, fn := range handlers {
+		if err := fn(req.Context(), webhook); err != nil {
+			// Allow the platform to retry the delivery
+			r.forget(keys)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// verify checks the signature or the secret of a request, returning the
+// signature and timestamp of signed requests.
+func (r *WebhookReceiver) verify(header http.Header, body []byte, now time.Time) (string, time.Time, error) {
+	if r.options.Secret == "" {
+		return "", time.Time{}, nil
+	}
+
+	if signature := header.Get(WEBHOOK_HEADER_SIGNATURE); signature != "" {
+		ts, err := strconv.ParseInt(header.Get(WEBHOOK_HEADER_TIMESTAMP), 10, 64)
+		if err != nil {
+			return "", time.Time{}, errors.New("invalid timestamp")
+		}
+
+		at := time.Unix(ts, 0)
+		if now.Sub(at).Abs() > r.options.Tolerance {
+			return "", time.Time{}, errors.New("timestamp outside tolerance")
+		}
+
+		expected := SignWebhook(r.options.Secret, at, body)
+		if !hmac.Equal([]byte(strings.ToLower(signature)), []byte(expected)) {
+			return "", time.Time{}, errors.New("invalid signature")
+		}
+		return expected, at, nil
+	}
+
+	if secret := header.Get(WEBHOOK_HEADER_SECRET); secret != "" {
+		if subtle.ConstantTimeCompare([]byte(secret), []byte(r.options.Secret)) != 1 {
+			return "", time.Time{}, errors.New("invalid secret")
+		}
+		return "", time.Time{}, nil
+	}
+
+	return "", time.Time{}, errors.New("missing signature")
+}
+
+// remember records the keys identifying a request until they expire,
+// reporting false if any of them was already seen.
+func (r *WebhookReceiver) remember(keys map[string]time.Time, now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key, expires := range r.seen {
+		if now.After(expires) {
+			delete(r.seen, key)
+		}
+	}
+
+	for key := range keys {
+		if _, ok := r.seen[key]; ok {
+			return false
+		}
+	}
+	for key, expires := range keys {
+		r.seen[key] = expires
+	}
+
+	return true
+}
+
+func (r *WebhookReceiver) forget(keys map[string]time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key := range keys {
+		delete(r.seen, key)
+	}
+}
+
+// parseWebhook decodes the body of a webhook call. Bodies without a payload
+// field are passed entirely as payload.
+func parseWebhook(body []byte) (WebhookRequest, error) {
+	var webhook WebhookRequest
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return webhook, fmt.Errorf("goplatform: webhook: %w", err)
+	}
+
+	if err := json.Unmarshal(body, &webhook); err != nil {
+		return webhook, fmt.Errorf("goplatform: webhook: %w", err)
+	}
+
+	if _, ok := raw["payload"]; !ok {
+		webhook.Payload = body
+	}
+
+	return webhook, nil
+}
+
+// SignWebhook returns the signature of a webhook body sent at timestamp, in
+// the form "sha256=<hex>".
+func SignWebhook(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10))) //nolint:errcheck
+	mac.Write([]byte("."))                                     //nolint:errcheck
+	mac.Write(body)                                            //nolint:errcheck
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+var _ http.Handler = (*WebhookReceiver)(nil)